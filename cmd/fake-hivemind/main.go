@@ -0,0 +1,36 @@
+// Command fake-hivemind serves an in-memory copy of the HiveMind tournament API
+// seeded from JSON fixtures, so hm-drafter can be run without touching real
+// tournaments:
+//
+//	go run ./cmd/fake-hivemind -fixtures fixtures/hivemind.json
+//	go run ./cmd/hm-drafter -hivemind-url http://localhost:8001/api
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+func main() {
+	addr := flag.String("addr", ":8001", "address to listen on")
+	fixtures := flag.String("fixtures", "fixtures/hivemind.json", "JSON file with seed data")
+	pageSize := flag.Int("page-size", hivemindtest.DefaultPageSize, "results per page on list endpoints")
+	flag.Parse()
+
+	fx, err := hivemindtest.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	fake := hivemindtest.NewServer(fx)
+	fake.PageSize = *pageSize
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", fake))
+
+	log.Printf("Fake HiveMind listening on %v (API root: http://localhost%v/api)", *addr, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
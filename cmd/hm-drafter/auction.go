@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	turnsMode   = "turns"
+	auctionMode = "auction"
+)
+
+// Auction is the state of an auction (salary-cap) draft. Captains take turns nominating a player, then everyone bids points on them until the countdown runs out.
+type Auction struct {
+	Budget          int            // Points each captain starts with
+	MinBid          int            // Smallest bid, and the opening bid on every nomination
+	BidSeconds      int            // Countdown after each bid
+	RosterSize      int            // Players each captain needs to buy, not counting themselves
+	Budgets         map[string]int // Points left, keyed by captain ID
+	NominationIndex int            // Position in the draft order of the next captain to nominate
+	Lot             *AuctionLot    // Player currently up for bids, nil between nominations
+}
+
+// AuctionLot is the player currently being bid on
+type AuctionLot struct {
+	PlayerID       float64
+	PlayerName     string
+	NominatedBy    string
+	HighBid        int
+	HighBidderID   float64
+	HighBidderName string
+	Deadline       time.Time
+}
+
+// AuctionStanding is one captain's line on the auction board
+type AuctionStanding struct {
+	Captain  Captain
+	Budget   int
+	Won      int
+	Open     int
+	MaxBid   int
+	Roster   []Pick
+	Nominate bool
+}
+
+// StartAuction switches the draft to auction mode. Every captain gets the same budget and needs to fill an equal share of the pool.
+func (d *Draft) StartAuction(budget, minBid, bidSeconds int) error {
+	if len(d.Picks) > 0 {
+		return fmt.Errorf("the draft mode can't change once picks have been made")
+	}
+	if minBid < 1 {
+		return fmt.Errorf("the minimum bid has to be at least 1")
+	}
+	if bidSeconds < 5 {
+		return fmt.Errorf("the bid countdown has to be at least 5 seconds")
+	}
+
+	captains := len(d.DraftOrder)
+	if captains == 0 {
+		return fmt.Errorf("choose the captains and confirm their teams before starting an auction")
+	}
+	rosterSize := (len(d.DraftPlayers) + captains - 1) / captains
+
+	// Everyone has to be able to fill their roster at the minimum bid
+	if budget < rosterSize*minBid {
+		return fmt.Errorf("a budget of %d can't fill a roster of %d at a minimum bid of %d", budget, rosterSize, minBid)
+	}
+
+	d.Mode = auctionMode
+	d.Auction = &Auction{
+		Budget:     budget,
+		MinBid:     minBid,
+		BidSeconds: bidSeconds,
+		RosterSize: rosterSize,
+		Budgets:    make(map[string]int),
+	}
+	for _, captain := range d.DraftOrder {
+		d.Auction.Budgets[formatID(captain.ID)] = budget
+	}
+
+	return nil
+}
+
+// won counts the players a captain has bought
+func (d *Draft) won(captainID float64) (won int) {
+	for _, pick := range d.Picks {
+		if pick.CaptainID == captainID {
+			won++
+		}
+	}
+	return won
+}
+
+// MaxBid is the most a captain can bid right now while still being able to fill the rest of their roster at the minimum bid. It's 0 when their roster is full.
+func (d *Draft) MaxBid(captainID float64) int {
+	a := d.Auction
+	open := a.RosterSize - d.won(captainID)
+	if open <= 0 {
+		return 0
+	}
+	return a.Budgets[formatID(captainID)] - a.MinBid*(open-1)
+}
+
+// Nominator returns the captain whose turn it is to nominate, skipping anyone with a full roster
+func (d *Draft) Nominator() (Captain, bool) {
+	for i := 0; i < len(d.DraftOrder); i++ {
+		captain := d.DraftOrder[(d.Auction.NominationIndex+i)%len(d.DraftOrder)]
+		if d.MaxBid(captain.ID) > 0 {
+			return captain, true
+		}
+	}
+	return Captain{}, false
+}
+
+// Standings returns every captain's budget and roster for the auction board
+func (d *Draft) Standings() (standings []AuctionStanding) {
+	nominator, _ := d.Nominator()
+
+	for _, captain := range d.DraftOrder {
+		standing := AuctionStanding{
+			Captain:  captain,
+			Budget:   d.Auction.Budgets[formatID(captain.ID)],
+			MaxBid:   d.MaxBid(captain.ID),
+			Nominate: d.Auction.Lot == nil && captain.ID == nominator.ID,
+		}
+		for _, pick := range d.Picks {
+			if pick.CaptainID == captain.ID {
+				standing.Roster = append(standing.Roster, pick)
+			}
+		}
+		standing.Won = len(standing.Roster)
+		standing.Open = d.Auction.RosterSize - standing.Won
+		standings = append(standings, standing)
+	}
+
+	return standings
+}
+
+// Nominate puts a player up for bids on behalf of the captain whose turn it is. The nominator opens the bidding at the minimum bid.
+func (d *Draft) Nominate(playerID float64) error {
+	a := d.Auction
+	if a.Lot != nil {
+		return fmt.Errorf("%v is still up for bids", a.Lot.PlayerName)
+	}
+
+	player, ok := d.poolPlayer(playerID)
+	if !ok {
+		return errAlreadyDrafted
+	}
+	nominator, ok := d.Nominator()
+	if !ok {
+		return fmt.Errorf("every captain's roster is full")
+	}
+
+	a.Lot = &AuctionLot{
+		PlayerID:       player.ID,
+		PlayerName:     player.Name,
+		NominatedBy:    nominator.Name,
+		HighBid:        a.MinBid,
+		HighBidderID:   nominator.ID,
+		HighBidderName: nominator.Name,
+		Deadline:       time.Now().Add(time.Duration(a.BidSeconds) * time.Second),
+	}
+	log.Printf("Draft %v: %v nominated %v", d.ID, nominator.Name, player.Name)
+	d.publish("auction", a.Lot)
+
+	return nil
+}
+
+// Bid raises the current lot's price. Each bid restarts the countdown.
+func (d *Draft) Bid(captainID float64, amount int) error {
+	a := d.Auction
+	if a.Lot == nil || time.Now().After(a.Lot.Deadline) {
+		return fmt.Errorf("bidding is closed")
+	}
+
+	idx := d.captainIndex(captainID)
+	if idx < 0 {
+		return fmt.Errorf("only captains can bid")
+	}
+	captain := d.DraftOrder[idx]
+
+	if amount <= a.Lot.HighBid {
+		return fmt.Errorf("bids have to beat the current high bid of %d", a.Lot.HighBid)
+	}
+	if maxBid := d.MaxBid(captainID); amount > maxBid {
+		if maxBid == 0 {
+			return fmt.Errorf("%v's roster is already full", captain.Name)
+		}
+		return fmt.Errorf("%v can bid at most %d and still fill their roster", captain.Name, maxBid)
+	}
+
+	a.Lot.HighBid = amount
+	a.Lot.HighBidderID = captain.ID
+	a.Lot.HighBidderName = captain.Name
+	a.Lot.Deadline = time.Now().Add(time.Duration(a.BidSeconds) * time.Second)
+	d.publish("auction", a.Lot)
+
+	return nil
+}
+
+// CloseLot sells the current player to the high bidder, writing them to the captain's HiveMind team through the same path as a turn-based pick
+func (d *Draft) CloseLot(ctx context.Context) (Pick, error) {
+	a := d.Auction
+	if a == nil || a.Lot == nil {
+		return Pick{}, fmt.Errorf("there's no player up for bids")
+	}
+	lot := a.Lot
+
+	player, ok := d.poolPlayer(lot.PlayerID)
+	if !ok {
+		a.Lot = nil
+		return Pick{}, errAlreadyDrafted
+	}
+	idx := d.captainIndex(lot.HighBidderID)
+	if idx < 0 {
+		// The high bidder was dropped as a captain since bidding, so nobody can win the lot
+		a.Lot = nil
+		return Pick{}, fmt.Errorf("%v isn't a captain anymore, nominate %v again", lot.HighBidderName, lot.PlayerName)
+	}
+	captain := d.DraftOrder[idx]
+
+	teamID, err := AddPlayerToDraftTeam(ctx, d.teamStore(), d.Teams, captain, player)
+	if err != nil {
+		return Pick{}, err
+	}
+	log.Printf("Draft %v: %v bought %v for %d", d.ID, captain.Name, player.Name, lot.HighBid)
+
+	a.Budgets[formatID(captain.ID)] -= lot.HighBid
+	a.Lot = nil
+	a.NominationIndex = (a.NominationIndex + 1) % len(d.DraftOrder)
+
+	pick := d.recordPick(player, captain, teamID, lot.HighBid)
+	d.refreshTeams(ctx)
+
+	return pick, nil
+}
+
+// scheduleLotClose sells the current lot once its countdown runs out. Bids push the deadline back, so the timer checks it again before selling.
+func (r *DraftRegistry) scheduleLotClose(d *Draft) {
+	if d.Auction == nil || d.Auction.Lot == nil {
+		return
+	}
+
+	r.schedule(d, d.Auction.Lot.Deadline, func(d *Draft) {
+		if d.Auction == nil || d.Auction.Lot == nil {
+			return
+		}
+		if time.Now().Before(d.Auction.Lot.Deadline) {
+			r.scheduleLotClose(d)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if _, err := d.CloseLot(ctx); err != nil && !errors.Is(err, errAlreadyDrafted) {
+			// Leave the lot open so the organizer can retry from the auction page
+			log.Printf("Draft %v: closing auction lot: %v", d.ID, err)
+		}
+	})
+}
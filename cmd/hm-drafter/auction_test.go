@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// auctionDraft is a local draft of 8 players, 2 captains each buying 3, with budgets of 10 and a minimum bid of 1
+func auctionDraft(t *testing.T) *Draft {
+	t.Helper()
+	d := localDraft(t, 8, 2)
+	if err := d.StartAuction(10, 1, 30); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestStartAuction(t *testing.T) {
+	tests := []struct {
+		name       string
+		budget     int
+		minBid     int
+		bidSeconds int
+		captains   int
+		wantErr    bool
+	}{
+		{name: "valid", budget: 100, minBid: 1, bidSeconds: 30, captains: 2},
+		{name: "no captains", budget: 100, minBid: 1, bidSeconds: 30, captains: 0, wantErr: true},
+		{name: "budget exactly fills the roster", budget: 6, minBid: 2, bidSeconds: 5, captains: 2},
+		{name: "budget too small", budget: 5, minBid: 2, bidSeconds: 30, captains: 2, wantErr: true},
+		{name: "no minimum bid", budget: 100, minBid: 0, bidSeconds: 30, captains: 2, wantErr: true},
+		{name: "countdown too short", budget: 100, minBid: 1, bidSeconds: 4, captains: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := localDraft(t, 8, tt.captains)
+			err := d.StartAuction(tt.budget, tt.minBid, tt.bidSeconds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if d.Mode != auctionMode || d.Auction.RosterSize != 3 {
+				t.Errorf("got mode %v and roster size %v, want an auction filling 3 each", d.Mode, d.Auction.RosterSize)
+			}
+			for _, captain := range d.DraftOrder {
+				if got := d.Auction.Budgets[formatID(captain.ID)]; got != tt.budget {
+					t.Errorf("%v starts with %v points, want %v", captain.Name, got, tt.budget)
+				}
+			}
+		})
+	}
+}
+
+func TestBid(t *testing.T) {
+	tests := []struct {
+		name      string
+		captainID float64
+		amount    int
+		wantErr   bool
+	}{
+		{name: "outbid", captainID: 2, amount: 2},
+		{name: "bid everything but the rest of the roster", captainID: 2, amount: 8},
+		{name: "more than leaves room for the roster", captainID: 2, amount: 9, wantErr: true},
+		{name: "matching the high bid", captainID: 2, amount: 1, wantErr: true},
+		{name: "not a captain", captainID: 5, amount: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := auctionDraft(t)
+			if err := d.Nominate(3); err != nil {
+				t.Fatal(err)
+			}
+
+			err := d.Bid(tt.captainID, tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			lot := d.Auction.Lot
+			if err == nil && (lot.HighBid != tt.amount || lot.HighBidderID != tt.captainID) {
+				t.Errorf("high bid is %v from %v, want %v from %v", lot.HighBid, lot.HighBidderID, tt.amount, tt.captainID)
+			}
+			if err != nil && (lot.HighBid != 1 || lot.HighBidderID != 1) {
+				t.Errorf("a refused bid changed the high bid to %v from %v", lot.HighBid, lot.HighBidderID)
+			}
+		})
+	}
+}
+
+func TestAuctionSaleAndUndo(t *testing.T) {
+	ctx := context.Background()
+	d := auctionDraft(t)
+
+	if err := d.Nominate(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Bid(2, 4); err != nil {
+		t.Fatal(err)
+	}
+	pick, err := d.CloseLot(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pick.CaptainID != 2 || pick.PlayerID != 3 || pick.Price != 4 {
+		t.Fatalf("got %+v, want captain 2 buying player 3 for 4", pick)
+	}
+	if got := d.Auction.Budgets["2"]; got != 6 {
+		t.Errorf("captain 2 has %v points left, want 6", got)
+	}
+	if got := d.MaxBid(2); got != 5 {
+		t.Errorf("captain 2 can bid %v, want 5 leaving 1 each for their last 1", got)
+	}
+	if nominator, _ := d.Nominator(); nominator.ID != 2 {
+		t.Errorf("%v nominates next, want captain 2", nominator.Name)
+	}
+
+	// Undoing the sale refunds the points and hands the nomination back
+	if _, err := d.UndoLastPick(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Auction.Budgets["2"]; got != 10 {
+		t.Errorf("captain 2 has %v points after the undo, want 10", got)
+	}
+	if nominator, _ := d.Nominator(); nominator.ID != 1 {
+		t.Errorf("%v nominates after the undo, want captain 1 again", nominator.Name)
+	}
+	if _, ok := d.poolPlayer(3); !ok {
+		t.Error("player 3 isn't back in the pool after the undo")
+	}
+}
+
+func TestCloseLotWithoutHighBidder(t *testing.T) {
+	d := auctionDraft(t)
+	if err := d.Nominate(3); err != nil {
+		t.Fatal(err)
+	}
+
+	// The high bidder stops being a captain before the lot closes
+	d.Auction.Lot.HighBidderID = 99
+	if _, err := d.CloseLot(context.Background()); err == nil {
+		t.Fatal("selling to someone who isn't a captain didn't return an error")
+	}
+	if d.Auction.Lot != nil || len(d.Picks) != 0 {
+		t.Error("the lot should be dropped without a sale")
+	}
+	if _, ok := d.poolPlayer(3); !ok {
+		t.Error("player 3 should still be in the pool")
+	}
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const autoMode = "auto"
+
+// BalanceProposal is a set of teams built by the optimizer, waiting for the organizer to accept it
+type BalanceProposal struct {
+	Teams         []ProposedTeam
+	RequiredRoles []string
+	BrokenPairs   []PairRequest
+	Metrics       BalanceMetrics
+	Rated         bool // Skill comes from HiveMind ratings where players have them
+	Seed          int64
+}
+
+// ProposedTeam is one team in a proposal
+type ProposedTeam struct {
+	Name         string
+	TeamID       int // The existing team the players go to, or 0 to create one when the proposal is accepted
+	Players      []Player
+	TotalSkill   float64
+	AverageSkill float64
+	RoleCounts   map[string]int
+	MissingRoles []string
+	Rules        []RuleStatus
+}
+
+// BalanceMetrics summarizes how even a proposal is
+type BalanceMetrics struct {
+	SkillStdDev  float64 // Standard deviation of the teams' average skill
+	SkillSpread  float64 // Gap between the strongest and weakest team's average skill
+	MissingRoles int     // Team/role pairs where no one on the team plays a required role
+	RuleMisses   int     // Players short of or over the roster rules, added up across teams
+	BrokenPairs  int     // Pair requests the teams don't honor
+	Repeats      int     // Teammate pairings that already happened in the scene's recent tournaments, when mixing it up
+	Cost         float64
+}
+
+// balanceIterations is how many swaps the annealer tries. A mixer-sized pool settles well before this.
+const balanceIterations = 20000
+
+// playerSkill reads the first number out of a player's self-reported skill, or 0 if there isn't one
+func playerSkill(player Player) float64 {
+	skill, _ := player.Field("skill").Number()
+	return skill
+}
+
+// playerRoles lists the roles a player plays
+func playerRoles(player Player) []string {
+	return player.Field("roles").Items()
+}
+
+// coverableRoles returns the roles enough players play for every team to get one
+func coverableRoles(players []Player, teams int) (roles []string) {
+	counts := make(map[string]int)
+	for _, player := range players {
+		for _, role := range playerRoles(player) {
+			counts[role]++
+		}
+	}
+	for role, count := range counts {
+		if count >= teams {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// BalanceTeams splits players into the given number of teams, keeping team sizes within one of each other while evening out skill and making sure every team covers the required roles, meets the roster rules and honors the pair requests. With a co-play history, recent teammates are split up where it doesn't cost much balance. With ratings, skill comes from them instead of what players reported. Players are snake-seeded by skill, then improved by simulated annealing over swaps.
+func BalanceTeams(players []Player, teamCount int, requiredRoles []string, rules []RosterRule, pairs []PairRequest, history *CoPlayHistory, ratings *Ratings, seed int64) (*BalanceProposal, error) {
+	if teamCount < 2 {
+		return nil, fmt.Errorf("at least 2 teams are needed")
+	}
+	if len(players) < teamCount {
+		return nil, fmt.Errorf("%d players can't fill %d teams", len(players), teamCount)
+	}
+
+	r := rand.New(rand.NewSource(seed))
+
+	// Snake-seed by skill so the starting point is already close. Shuffling first breaks ties randomly.
+	seeded := append([]Player(nil), players...)
+	r.Shuffle(len(seeded), func(i, j int) { seeded[i], seeded[j] = seeded[j], seeded[i] })
+	sort.SliceStable(seeded, func(i, j int) bool { return ratings.Skill(seeded[i]) > ratings.Skill(seeded[j]) })
+
+	assignment := make([][]Player, teamCount)
+	for _, slot := range (SnakeFormat{}).Sequence(teamCount, len(seeded)) {
+		assignment[slot.CaptainIndex] = append(assignment[slot.CaptainIndex], seeded[slot.Overall-1])
+	}
+
+	cost := balanceCost(assignment, requiredRoles, rules, pairs, history, ratings)
+	best := cloneAssignment(assignment)
+	bestCost := cost
+
+	// Anneal: swap two players on different teams, always keeping improvements and sometimes keeping a worse swap early on to escape local minimums
+	for i := 0; i < balanceIterations && bestCost > 0; i++ {
+		temperature := 1.0 - float64(i)/balanceIterations
+
+		a, b := r.Intn(teamCount), r.Intn(teamCount)
+		if a == b {
+			continue
+		}
+		x, y := r.Intn(len(assignment[a])), r.Intn(len(assignment[b]))
+
+		assignment[a][x], assignment[b][y] = assignment[b][y], assignment[a][x]
+		next := balanceCost(assignment, requiredRoles, rules, pairs, history, ratings)
+
+		if next <= cost || r.Float64() < math.Exp((cost-next)/(temperature+1e-9)) {
+			cost = next
+			if cost < bestCost {
+				bestCost = cost
+				best = cloneAssignment(assignment)
+			}
+		} else {
+			assignment[a][x], assignment[b][y] = assignment[b][y], assignment[a][x]
+		}
+	}
+
+	proposal := &BalanceProposal{RequiredRoles: requiredRoles, BrokenPairs: brokenPairs(pairs, best), Rated: ratings != nil, Seed: seed}
+	for i, team := range best {
+		proposal.Teams = append(proposal.Teams, describeTeam(fmt.Sprintf("Team %d", i+1), team, requiredRoles, ruleStatuses(rules, team, players, teamCount), ratings))
+	}
+	proposal.Metrics = balanceMetrics(best, requiredRoles, rules, pairs, history, ratings)
+
+	return proposal, nil
+}
+
+// balanceCost scores an assignment, lower is better. A missing role, a player off a roster rule or a broken pair request costs more than any realistic skill gap.
+func balanceCost(teams [][]Player, requiredRoles []string, rules []RosterRule, pairs []PairRequest, history *CoPlayHistory, ratings *Ratings) float64 {
+	metrics := balanceMetrics(teams, requiredRoles, rules, pairs, history, ratings)
+	return metrics.Cost
+}
+
+func balanceMetrics(teams [][]Player, requiredRoles []string, rules []RosterRule, pairs []PairRequest, history *CoPlayHistory, ratings *Ratings) (metrics BalanceMetrics) {
+	var everyone []Player
+	for _, team := range teams {
+		everyone = append(everyone, team...)
+	}
+
+	averages := make([]float64, len(teams))
+	for i, team := range teams {
+		averages[i] = teamAverageSkill(team, ratings)
+		metrics.MissingRoles += len(missingRoles(team, requiredRoles))
+		metrics.Repeats += history.teamRepeats(team)
+		for _, status := range ruleStatuses(rules, team, everyone, len(teams)) {
+			metrics.RuleMisses += status.Off
+		}
+	}
+
+	mean := 0.0
+	for _, avg := range averages {
+		mean += avg
+	}
+	mean /= float64(len(averages))
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, avg := range averages {
+		metrics.SkillStdDev += (avg - mean) * (avg - mean)
+		low = math.Min(low, avg)
+		high = math.Max(high, avg)
+	}
+	metrics.SkillStdDev = math.Sqrt(metrics.SkillStdDev / float64(len(averages)))
+	metrics.SkillSpread = high - low
+	metrics.BrokenPairs = len(brokenPairs(pairs, teams))
+	metrics.Cost = metrics.SkillStdDev + 5*float64(metrics.MissingRoles+metrics.RuleMisses+metrics.BrokenPairs) + mixPenalty*float64(metrics.Repeats)
+
+	return metrics
+}
+
+func teamAverageSkill(team []Player, ratings *Ratings) float64 {
+	if len(team) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, player := range team {
+		total += ratings.Skill(player)
+	}
+	return total / float64(len(team))
+}
+
+// missingRoles lists the required roles nobody on the team plays
+func missingRoles(team []Player, requiredRoles []string) (missing []string) {
+	for _, role := range requiredRoles {
+		covered := false
+		for _, player := range team {
+			for _, playerRole := range playerRoles(player) {
+				if strings.EqualFold(playerRole, role) {
+					covered = true
+				}
+			}
+		}
+		if !covered {
+			missing = append(missing, role)
+		}
+	}
+	return missing
+}
+
+func describeTeam(name string, players []Player, requiredRoles []string, rules []RuleStatus, ratings *Ratings) ProposedTeam {
+	team := ProposedTeam{
+		Name:         name,
+		Players:      players,
+		AverageSkill: teamAverageSkill(players, ratings),
+		RoleCounts:   make(map[string]int),
+		MissingRoles: missingRoles(players, requiredRoles),
+		Rules:        rules,
+	}
+	for _, player := range players {
+		team.TotalSkill += ratings.Skill(player)
+		for _, role := range playerRoles(player) {
+			team.RoleCounts[role]++
+		}
+	}
+	return team
+}
+
+func cloneAssignment(teams [][]Player) [][]Player {
+	clone := make([][]Player, len(teams))
+	for i, team := range teams {
+		clone[i] = append([]Player(nil), team...)
+	}
+	return clone
+}
+
+// ProposeBalancedTeams switches the draft to automatic balancing and builds a fresh proposal from every registered player
+func (d *Draft) ProposeBalancedTeams(ctx context.Context, teamCount int) error {
+	if len(d.Picks) > 0 {
+		return fmt.Errorf("players have already been drafted, balancing would overwrite the picks")
+	}
+
+	// Teams may have been added or removed since the page loaded, so fill the ones there are now
+	teams, err := d.teamStore().Teams(ctx, d.Players)
+	if err != nil {
+		return fmt.Errorf("fetching teams: %w", err)
+	}
+	d.Teams = teams
+
+	proposal, err := BalanceTeams(d.Players, teamCount, coverableRoles(d.Players, teamCount), d.RosterRules, d.PairRequests(), d.coPlay(), d.ratings(), time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+
+	// Fill the teams that already exist in HiveMind, keeping their names
+	for i := range proposal.Teams {
+		if i < len(d.Teams) {
+			proposal.Teams[i].Name = d.Teams[i].Name
+			proposal.Teams[i].TeamID = d.Teams[i].ID
+		}
+	}
+
+	d.Mode = autoMode
+	d.Proposal = proposal
+	d.DraftPlayers = append([]Player(nil), d.Players...)
+
+	return nil
+}
+
+// AcceptProposal writes the proposed teams to the team store, reusing the tournament's existing teams before creating new ones
+func (d *Draft) AcceptProposal(ctx context.Context) error {
+	if d.Proposal == nil {
+		return fmt.Errorf("there's no proposal to accept")
+	}
+
+	store := d.teamStore()
+	teams, err := store.Teams(ctx, d.Players)
+	if err != nil {
+		return err
+	}
+
+	// Check every team the proposal fills is still there before anyone is moved
+	for _, proposed := range d.Proposal.Teams {
+		if proposed.TeamID != 0 && !hasTeam(teams, proposed.TeamID) {
+			return fmt.Errorf("%v no longer exists, balance the teams again", proposed.Name)
+		}
+	}
+
+	for i, proposed := range d.Proposal.Teams {
+		teamID := proposed.TeamID
+		if teamID == 0 {
+			teamID, err = store.AddTeam(ctx, proposed.Name)
+			if err != nil {
+				return fmt.Errorf("creating %v: %w", proposed.Name, err)
+			}
+			// Remember the new team straight away, so accepting again after a failure fills it instead of adding another
+			d.Proposal.Teams[i].TeamID = teamID
+		}
+
+		for _, player := range proposed.Players {
+			if err := store.AssignPlayer(ctx, formatID(player.ID), strconv.Itoa(teamID)); err != nil {
+				return err
+			}
+			d.setPlayerTeam(player.ID, teamID)
+			d.DraftPlayers = RemoveDraftedPlayers(d.DraftPlayers, player.ID)
+		}
+	}
+	log.Printf("Draft %v: accepted %d balanced teams", d.ID, len(d.Proposal.Teams))
+
+	d.refreshTeams(ctx)
+	return nil
+}
+
+// hasTeam reports whether a team with the given ID is in the list
+func hasTeam(teams []TeamInfo, teamID int) bool {
+	for _, team := range teams {
+		if team.ID == teamID {
+			return true
+		}
+	}
+	return false
+}
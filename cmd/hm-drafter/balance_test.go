@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// balancePlayers makes n players with skills running 1 to 5, every fourth one a Queen
+func balancePlayers(n int) (players []Player) {
+	for i := 1; i <= n; i++ {
+		role := "Speed Warrior"
+		if i%4 == 0 {
+			role = "Queen"
+		}
+		players = append(players, Player{
+			ID:   float64(i),
+			Name: fmt.Sprintf("Player %d", i),
+			FormFields: map[string]FieldValue{
+				"skill": NumberValue(float64((i-1)%5 + 1)),
+				"roles": ListValue([]string{role}),
+			},
+		})
+	}
+	return players
+}
+
+// proposedTeamOf finds which team in a proposal a player is on, or -1
+func proposedTeamOf(proposal *BalanceProposal, playerID float64) int {
+	for i, team := range proposal.Teams {
+		for _, player := range team.Players {
+			if player.ID == playerID {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func TestBalanceTeams(t *testing.T) {
+	tests := []struct {
+		name      string
+		players   int
+		teams     int
+		roles     []string
+		pairs     []PairRequest
+		maxSpread float64
+	}{
+		{name: "even split", players: 12, teams: 3, maxSpread: 0.5},
+		{name: "uneven split", players: 13, teams: 4, maxSpread: 1},
+		{name: "every team gets a queen", players: 16, teams: 4, roles: []string{"Queen"}, maxSpread: 1},
+		{
+			name: "pair requests", players: 10, teams: 2, maxSpread: 1,
+			pairs: []PairRequest{
+				{Kind: "together", PlayerA: 1, PlayerB: 2},
+				{Kind: "apart", PlayerA: 5, PlayerB: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			players := balancePlayers(tt.players)
+			proposal, err := BalanceTeams(players, tt.teams, tt.roles, nil, tt.pairs, nil, nil, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(proposal.Teams) != tt.teams {
+				t.Fatalf("got %d teams, want %d", len(proposal.Teams), tt.teams)
+			}
+
+			// Everyone is placed exactly once and team sizes are within one of each other
+			smallest, largest := math.MaxInt, 0
+			placed := 0
+			for _, team := range proposal.Teams {
+				smallest, largest = min(smallest, len(team.Players)), max(largest, len(team.Players))
+				placed += len(team.Players)
+				if len(team.MissingRoles) > 0 {
+					t.Errorf("%v has no %v", team.Name, team.MissingRoles)
+				}
+			}
+			if placed != tt.players {
+				t.Errorf("placed %d players, want %d", placed, tt.players)
+			}
+			for _, player := range players {
+				if proposedTeamOf(proposal, player.ID) < 0 {
+					t.Errorf("%v isn't on a team", player.Name)
+				}
+			}
+			if largest-smallest > 1 {
+				t.Errorf("team sizes run from %d to %d", smallest, largest)
+			}
+
+			if proposal.Metrics.SkillSpread > tt.maxSpread {
+				t.Errorf("average skills are %.2f apart, want at most %.2f", proposal.Metrics.SkillSpread, tt.maxSpread)
+			}
+			if len(proposal.BrokenPairs) > 0 {
+				t.Errorf("broke pair requests %v", proposal.BrokenPairs)
+			}
+		})
+	}
+}
+
+func TestBalanceTeamsErrors(t *testing.T) {
+	tests := []struct {
+		players int
+		teams   int
+	}{
+		{players: 10, teams: 1},
+		{players: 3, teams: 4},
+	}
+	for _, tt := range tests {
+		if _, err := BalanceTeams(balancePlayers(tt.players), tt.teams, nil, nil, nil, nil, nil, 1); err == nil {
+			t.Errorf("%d players in %d teams didn't return an error", tt.players, tt.teams)
+		}
+	}
+}
+
+func TestAcceptProposal(t *testing.T) {
+	ctx := context.Background()
+	d := localDraft(t, 9, 0)
+	d.Players = balancePlayers(9)
+	store := d.teamStore()
+
+	// One team already exists and isn't the first one added, so its ID doesn't line up with its position
+	if _, err := store.AddTeam(ctx, "Gone"); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := store.AddTeam(ctx, "Kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DeleteTeam(ctx, "1", "Gone"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The draft's teams haven't been refreshed since, proposing fetches them
+	if err := d.ProposeBalancedTeams(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	if first := d.Proposal.Teams[0]; first.TeamID != kept || first.Name != "Kept" {
+		t.Fatalf("the first proposed team is %v (%v), want Kept (%v)", first.Name, first.TeamID, kept)
+	}
+	if err := d.AcceptProposal(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Players land on the team they were proposed for, and new teams are made for the rest
+	for _, team := range d.Proposal.Teams {
+		for _, proposed := range team.Players {
+			player, _ := d.playerByID(proposed.ID)
+			if team.TeamID != 0 && player.Team != team.TeamID {
+				t.Errorf("%v is on team %v, want %v", player.Name, player.Team, team.TeamID)
+			}
+			if player.Team == 0 {
+				t.Errorf("%v wasn't put on a team", player.Name)
+			}
+		}
+	}
+	if len(d.LocalTeams) != 3 || len(d.DraftPlayers) != 0 {
+		t.Errorf("got %d teams and %d players left, want 3 teams and nobody left", len(d.LocalTeams), len(d.DraftPlayers))
+	}
+}
+
+func TestAcceptProposalMissingTeam(t *testing.T) {
+	ctx := context.Background()
+	d := localDraft(t, 6, 0)
+	store := d.teamStore()
+	teamID, err := store.AddTeam(ctx, "Red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Teams, err = store.Teams(ctx, d.Players); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ProposeBalancedTeams(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The team is deleted before the proposal is accepted
+	if _, err := store.DeleteTeam(ctx, fmt.Sprint(teamID), "Red"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.AcceptProposal(ctx); err == nil {
+		t.Fatal("accepting a proposal for a deleted team didn't return an error")
+	}
+	for _, player := range d.Players {
+		if player.Team != 0 {
+			t.Errorf("%v was moved to team %v before the error", player.Name, player.Team)
+		}
+	}
+}
+
+func TestAcceptProposalRetry(t *testing.T) {
+	ctx := context.Background()
+	d := localDraft(t, 6, 0)
+	if err := d.ProposeBalancedTeams(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second team can't be made, so accepting fails after the first one is
+	second := &d.Proposal.Teams[1]
+	name := second.Name
+	second.Name = ""
+	if err := d.AcceptProposal(ctx); err == nil {
+		t.Fatal("accepting a team without a name didn't return an error")
+	}
+	if d.Proposal.Teams[0].TeamID == 0 {
+		t.Fatalf("the proposal doesn't remember the team it made: %+v", d.Proposal.Teams[0])
+	}
+
+	// Accepting again fills the team made the first time
+	second.Name = name
+	if err := d.AcceptProposal(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.LocalTeams) != 2 {
+		t.Errorf("got %d teams after retrying, want 2: %+v", len(d.LocalTeams), d.LocalTeams)
+	}
+	for _, player := range d.Players {
+		if player.Team == 0 {
+			t.Errorf("%v wasn't put on a team", player.Name)
+		}
+	}
+}
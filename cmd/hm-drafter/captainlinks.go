@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// captainCodeAlphabet leaves out letters and digits that are easy to mix up when read off a screen
+const captainCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+const captainCodeLength = 6
+
+// CaptainLink is a captain's private way into their pick page: a secret link to text them, or a short code to type in from the lobby
+type CaptainLink struct {
+	CaptainID   float64
+	CaptainName string
+	Token       string
+	Code        string
+}
+
+// URL is the captain's pick page
+func (l CaptainLink) URL(draftID string) string {
+	return "/drafts/" + draftID + "/captain/" + l.Token
+}
+
+// issueCaptainLinks gives every captain in the draft order a link. Captains who already had one keep it, so re-confirming captains doesn't break links that were already sent out.
+func (r *DraftRegistry) issueCaptainLinks(d *Draft) {
+	existing := make(map[float64]CaptainLink)
+	for _, link := range d.CaptainLinks {
+		existing[link.CaptainID] = link
+	}
+
+	var links []CaptainLink
+	for _, captain := range d.DraftOrder {
+		link, ok := existing[captain.ID]
+		if !ok {
+			link = CaptainLink{
+				CaptainID:   captain.ID,
+				CaptainName: captain.Name,
+				Token:       randomToken(16),
+				Code:        r.claimCaptainCode(d.ID),
+			}
+		}
+		delete(existing, captain.ID)
+		links = append(links, link)
+	}
+
+	// Captains who were dropped lose their access
+	for _, link := range existing {
+		r.releaseCaptainCode(link.Code)
+	}
+
+	d.CaptainLinks = links
+}
+
+// CaptainLinkByToken returns the link with the given secret token
+func (d *Draft) CaptainLinkByToken(token string) (CaptainLink, bool) {
+	for _, link := range d.CaptainLinks {
+		if subtle.ConstantTimeCompare([]byte(link.Token), []byte(token)) == 1 {
+			return link, true
+		}
+	}
+	return CaptainLink{}, false
+}
+
+// CaptainLinkByCode returns the link with the given short code, ignoring case
+func (d *Draft) CaptainLinkByCode(code string) (CaptainLink, bool) {
+	for _, link := range d.CaptainLinks {
+		if strings.EqualFold(link.Code, code) {
+			return link, true
+		}
+	}
+	return CaptainLink{}, false
+}
+
+// claimCaptainCode returns a short code no other captain in any active draft is using
+func (r *DraftRegistry) claimCaptainCode(draftID string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for {
+		code := randomCode(captainCodeLength)
+		if _, taken := r.codes[code]; !taken {
+			r.codes[code] = draftID
+			return code
+		}
+	}
+}
+
+func (r *DraftRegistry) releaseCaptainCode(code string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.codes, code)
+}
+
+// DraftForCaptainCode returns the draft a short code belongs to, or nil
+func (r *DraftRegistry) DraftForCaptainCode(code string) *Draft {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.drafts[r.codes[strings.ToUpper(strings.TrimSpace(code))]]
+}
+
+// randomToken returns n random bytes as hex, for links that shouldn't be guessable
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// randomCode returns a random code of the given length from captainCodeAlphabet
+func randomCode(length int) string {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(captainCodeAlphabet))))
+		if err != nil {
+			panic(err)
+		}
+		code[i] = captainCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
+
+// captainView gathers what a captain's pick page shows
+func (d *Draft) captainView(link CaptainLink) (captain Captain, roster []Player, myTurn bool) {
+	if idx := d.captainIndex(link.CaptainID); idx >= 0 {
+		captain = d.DraftOrder[idx]
+	}
+
+	teamID := CaptainTeamID(d.Teams, captain)
+	for _, team := range d.Teams {
+		if teamID != 0 && team.ID == teamID {
+			roster = team.Players
+		}
+	}
+
+	myTurn = d.Mode == turnsMode && !d.Finished() && d.peekCaptain().ID == link.CaptainID
+	return captain, roster, myTurn
+}
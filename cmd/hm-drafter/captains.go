@@ -41,7 +41,7 @@ func ExtractCaptains(captainNamesList []string, players []Player) (captains []Ca
 			captains = append(captains, Captain{
 				ID:      player.ID,
 				Name:    player.Name,
-				AltName: player.FormFields["altname"],
+				AltName: player.AltName(),
 			})
 		}
 	}
@@ -84,7 +84,7 @@ func GenerateDraftOrder(captains []Captain) (draftOrder []Captain) {
 
 
 // Adds or removes players from the unassignedCaptains var. If addCap bool is True, player list is searched and the one with the matching captain ID is appended. IF addCap is False, captain is removed from list.
-func UpdateUnassignedCaptains(captainID string, captains []Captain, addCap bool) (unassignedCaptains []Captain) {
+func UpdateUnassignedCaptains(captainID string, captains []Captain, players []Player, addCap bool) (unassignedCaptains []Captain) {
 	// Convert captainID from string to float64
 	id, err := strconv.ParseFloat(captainID, 64)
 	if err != nil {
@@ -94,12 +94,13 @@ func UpdateUnassignedCaptains(captainID string, captains []Captain, addCap bool)
 
 	// Search the full players list for the one with the same ID as the captain we're adding to the unassignedCaptains list
 	if addCap == true {
+		unassignedCaptains = append(unassignedCaptains, captains...)
 		for _, player := range players {
 			if id == player.ID {
 				unassignedCaptains = append(unassignedCaptains, Captain{
 					ID:      player.ID,
 					Name:    player.Name,
-					AltName: player.FormFields["altname"],
+					AltName: player.AltName(),
 				})
 				break // Only add one matching player
 			}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// minTurnLength is the shortest a turn can get, however the per-round escalation is set up
+const minTurnLength = 5 * time.Second
+
+// autoPickRetry is how long the clock waits before trying an auto-pick again after HiveMind rejects one
+const autoPickRetry = 10 * time.Second
+
+// PickClock is the server-side timer for turn-based drafts. When a captain's time runs out the server picks for them.
+type PickClock struct {
+	Seconds   int           // Time per pick in the first round
+	PerRound  int           // Seconds added (or taken away, if negative) each round after the first
+	Deadline  time.Time     // When the current turn runs out
+	Paused    bool          // Set by the organizer, stops the countdown
+	Remaining time.Duration // Time left on the turn when it was paused
+}
+
+// TurnLength returns how long captains get to pick in the given round
+func (c *PickClock) TurnLength(round int) time.Duration {
+	length := time.Duration(c.Seconds+c.PerRound*(round-1)) * time.Second
+	if length < minTurnLength {
+		return minTurnLength
+	}
+	return length
+}
+
+// SetClock turns the pick clock on, or off if seconds is 0
+func (d *Draft) SetClock(seconds, perRound int) error {
+	if seconds == 0 {
+		d.Clock = nil
+		return nil
+	}
+	if seconds < int(minTurnLength/time.Second) {
+		return fmt.Errorf("the pick clock has to be at least %v", minTurnLength)
+	}
+
+	d.Clock = &PickClock{Seconds: seconds, PerRound: perRound}
+	return nil
+}
+
+// clockRunning reports whether there's a turn for the clock to time
+func (d *Draft) clockRunning() bool {
+	return d.Clock != nil && d.Mode == turnsMode && len(d.DraftOrder) > 0 && !d.Finished()
+}
+
+// ClockRemaining returns the time left on the current turn, or 0 if there's no clock
+func (d *Draft) ClockRemaining() time.Duration {
+	if !d.clockRunning() {
+		return 0
+	}
+	if d.Clock.Paused {
+		return d.Clock.Remaining
+	}
+	if remaining := time.Until(d.Clock.Deadline); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// PauseClock stops the countdown, keeping the time left on the turn
+func (d *Draft) PauseClock() {
+	if !d.clockRunning() || d.Clock.Paused {
+		return
+	}
+	d.Clock.Remaining = d.ClockRemaining()
+	d.Clock.Paused = true
+	d.stopTimer()
+	d.publishClock()
+	log.Printf("Draft %v: pick clock paused with %v left", d.ID, d.Clock.Remaining.Round(time.Second))
+}
+
+// ResumeClock restarts the countdown from where it was paused
+func (d *Draft) ResumeClock() {
+	if !d.clockRunning() || !d.Clock.Paused {
+		return
+	}
+	d.Clock.Deadline = time.Now().Add(d.Clock.Remaining)
+	d.Clock.Paused = false
+	d.Clock.Remaining = 0
+	d.publishClock()
+	log.Printf("Draft %v: pick clock resumed", d.ID)
+}
+
+// stopTimer cancels whatever timer is pending for the draft
+func (d *Draft) stopTimer() {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+}
+
+// startPickClock gives the captain who's up a full turn. Call it whenever the turn changes.
+func (r *DraftRegistry) startPickClock(d *Draft) {
+	if !d.clockRunning() {
+		if d.Mode == turnsMode {
+			d.stopTimer()
+		}
+		return
+	}
+
+	length := d.Clock.TurnLength(d.CurrentSlot().Round)
+	if d.Clock.Paused {
+		d.Clock.Remaining = length
+		d.stopTimer()
+		d.publishClock()
+		return
+	}
+
+	d.Clock.Deadline = time.Now().Add(length)
+	r.scheduleAutoPick(d)
+	d.publishClock()
+}
+
+// scheduleAutoPick makes the current captain's pick for them once their turn runs out
+func (r *DraftRegistry) scheduleAutoPick(d *Draft) {
+	if !d.clockRunning() || d.Clock.Paused {
+		return
+	}
+
+	r.schedule(d, d.Clock.Deadline, func(d *Draft) {
+		if !d.clockRunning() || d.Clock.Paused {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if _, err := d.AutoPick(ctx); err != nil {
+			// Keep the turn open and try again shortly, the organizer can still pick by hand
+			log.Printf("Draft %v: auto-pick failed: %v", d.ID, err)
+			d.Clock.Deadline = time.Now().Add(autoPickRetry)
+			r.scheduleAutoPick(d)
+			return
+		}
+
+		r.startPickClock(d)
+	})
+}
+
+// AutoPick makes the current captain's pick for them: the first player still available in their queue, or else the most skilled player left in the pool, going by ratings when the draft uses them
+func (d *Draft) AutoPick(ctx context.Context) (Pick, error) {
+	player, ok := d.autoPickChoice(d.CurrentCaptain())
+	if !ok {
+		return Pick{}, fmt.Errorf("there's no one left to pick")
+	}
+
+	pick, err := d.PickPlayer(ctx, player.ID)
+	if err != nil {
+		return Pick{}, err
+	}
+
+	// Mark the pick as the clock's so the history shows who really made it
+	d.Picks[len(d.Picks)-1].Auto = true
+	d.History[len(d.History)-1].Pick.Auto = true
+	pick.Auto = true
+	log.Printf("Draft %v: time ran out, auto-picked %v for %v", d.ID, player.Name, pick.CaptainName)
+
+	return pick, nil
+}
+
+// autoPickChoice decides who the clock picks for a captain: the first player left in their queue, otherwise the highest skill player left, favoring anyone a teammate asked to play with and, when mixing it up, anyone who hasn't played with the team recently. Players who'd break a roster rule or join someone they asked to be kept apart from are passed over unless everyone would.
+func (d *Draft) autoPickChoice(captain Captain) (Player, bool) {
+	requests := d.PairRequests()
+	roster := d.captainRoster(captain)
+	history := d.coPlay()
+	ratings := d.ratings()
+
+	for _, strict := range []bool{true, false} {
+		fits := func(player Player) bool {
+			return !strict || (d.fitsRoster(player) && pairFit(requests, roster, player) >= 0)
+		}
+
+		for _, player := range d.Queue(captain.ID) {
+			if fits(player) {
+				return player, true
+			}
+		}
+
+		var best Player
+		bestFit, bestRepeats, found := 0, 0, false
+		for _, player := range d.DraftPlayers {
+			if !fits(player) {
+				continue
+			}
+			fit, repeats := pairFit(requests, roster, player), history.Repeats(roster, player)
+			better := fit > bestFit || (fit == bestFit && (repeats < bestRepeats || (repeats == bestRepeats && ratings.Skill(player) > ratings.Skill(best))))
+			if !found || better {
+				best, bestFit, bestRepeats = player, fit, repeats
+				found = true
+			}
+		}
+		if found {
+			return best, true
+		}
+	}
+	return Player{}, false
+}
+
+// Queue returns the players a captain has queued up who are still available, in the captain's order
+func (d *Draft) Queue(captainID float64) (queue []Player) {
+	for _, playerID := range d.Queues[formatID(captainID)] {
+		if player, ok := d.poolPlayer(playerID); ok {
+			queue = append(queue, player)
+		}
+	}
+	return queue
+}
+
+// QueuePlayer adds a player to the end of a captain's auto-pick queue
+func (d *Draft) QueuePlayer(captainID, playerID float64) error {
+	if d.captainIndex(captainID) < 0 {
+		return fmt.Errorf("only captains have a queue")
+	}
+	player, ok := d.poolPlayer(playerID)
+	if !ok {
+		return errAlreadyDrafted
+	}
+
+	key := formatID(captainID)
+	for _, queued := range d.Queues[key] {
+		if queued == playerID {
+			return nil
+		}
+	}
+
+	if d.Queues == nil {
+		d.Queues = make(map[string][]float64)
+	}
+	d.Queues[key] = append(d.Queues[key], player.ID)
+	return nil
+}
+
+// UnqueuePlayer takes a player off a captain's queue
+func (d *Draft) UnqueuePlayer(captainID, playerID float64) {
+	// No one has queued anyone yet, so there's nothing to take off
+	if d.Queues == nil {
+		return
+	}
+	key := formatID(captainID)
+
+	var queue []float64
+	for _, queued := range d.Queues[key] {
+		if queued != playerID {
+			queue = append(queue, queued)
+		}
+	}
+	d.Queues[key] = queue
+}
+
+// CaptainQueue is one captain's queue, for the drafting page
+type CaptainQueue struct {
+	Captain Captain
+	Players []Player
+}
+
+// CaptainQueues returns every captain's queue in draft order
+func (d *Draft) CaptainQueues() (queues []CaptainQueue) {
+	for _, captain := range d.DraftOrder {
+		queues = append(queues, CaptainQueue{Captain: captain, Players: d.Queue(captain.ID)})
+	}
+	return queues
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// clockDraft registers a local draft of 8 players and 2 captains with a pick clock running on the first turn
+func clockDraft(t *testing.T) *Draft {
+	t.Helper()
+
+	oldDrafts := drafts
+	drafts = NewDraftRegistry(nil)
+	t.Cleanup(func() { drafts = oldDrafts })
+
+	d := localDraft(t, 8, 2)
+	if err := d.SetClock(5, 0); err != nil {
+		t.Fatal(err)
+	}
+	drafts.drafts[d.ID] = d
+
+	d.mu.Lock()
+	drafts.startPickClock(d)
+	d.mu.Unlock()
+	t.Cleanup(func() {
+		d.mu.Lock()
+		d.stopTimer()
+		d.mu.Unlock()
+	})
+	return d
+}
+
+// runOutSoon cuts the current turn down to a few milliseconds, since real turns are at least 5 seconds
+func runOutSoon(d *Draft) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Clock.Deadline = time.Now().Add(20 * time.Millisecond)
+	drafts.scheduleAutoPick(d)
+}
+
+// waitForPicks waits until the draft has n picks, failing the test if it takes more than a couple of seconds
+func waitForPicks(t *testing.T, d *Draft, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		d.mu.Lock()
+		picks := len(d.Picks)
+		d.mu.Unlock()
+		if picks >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the draft has %d picks, want %d", picks, n)
+		}
+	}
+}
+
+// assertPicks checks the draft still has n picks once a turn that was cut short would have run out
+func assertPicks(t *testing.T, d *Draft, n int) {
+	t.Helper()
+	time.Sleep(100 * time.Millisecond)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.Picks) != n {
+		t.Errorf("the draft has %d picks, want %d", len(d.Picks), n)
+	}
+}
+
+func TestClockExpiry(t *testing.T) {
+	d := clockDraft(t)
+
+	d.mu.Lock()
+	if remaining := d.ClockRemaining(); remaining <= 4*time.Second || remaining > 5*time.Second {
+		t.Errorf("the first turn has %v left, want 5s", remaining)
+	}
+	d.mu.Unlock()
+
+	// When the turn runs out the clock picks for the captain and starts the next turn
+	runOutSoon(d)
+	waitForPicks(t, d, 1)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if pick := d.Picks[0]; !pick.Auto || pick.CaptainID != 1 {
+		t.Errorf("got pick %+v, want an auto-pick for the first captain", pick)
+	}
+	if d.CurrentCaptain().ID != 2 {
+		t.Errorf("%v is up after the auto-pick, want the second captain", d.CurrentCaptain().Name)
+	}
+	if remaining := d.ClockRemaining(); remaining <= 4*time.Second {
+		t.Errorf("the next turn has %v left, want a full turn", remaining)
+	}
+}
+
+func TestAutoPick(t *testing.T) {
+	tests := []struct {
+		name   string
+		queue  []float64 // The first captain's queue, set up before any picks
+		picks  []float64 // Made by hand before the clock runs out
+		want   float64
+		wantBy float64
+	}{
+		// The pool is players 3 to 8 with skills 3, 4, 5, 1, 2, 3
+		{name: "best available", want: 5, wantBy: 1},
+		{name: "first in the queue", queue: []float64{7, 4}, want: 7, wantBy: 1},
+		{name: "skipping queued players already taken", queue: []float64{7, 4}, picks: []float64{8, 7, 5}, want: 4, wantBy: 1},
+		{name: "best available once the queue is used up", queue: []float64{7}, picks: []float64{7, 5}, want: 4, wantBy: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			d := localDraft(t, 8, 2)
+			for _, id := range tt.queue {
+				if err := d.QueuePlayer(1, id); err != nil {
+					t.Fatal(err)
+				}
+				// Both captains queue the same players
+				if err := d.QueuePlayer(2, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, id := range tt.picks {
+				if _, err := d.PickPlayer(ctx, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			pick, err := d.AutoPick(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pick.PlayerID != tt.want || pick.CaptainID != tt.wantBy || !pick.Auto {
+				t.Errorf("got pick %+v, want player %v auto-picked by captain %v", pick, tt.want, tt.wantBy)
+			}
+			if last := d.Picks[len(d.Picks)-1]; !last.Auto {
+				t.Error("the pick isn't marked as the clock's in the draft")
+			}
+		})
+	}
+}
+
+func TestClockPauseResume(t *testing.T) {
+	d := clockDraft(t)
+
+	d.mu.Lock()
+	d.PauseClock()
+	paused := d.ClockRemaining()
+	if d.timer != nil {
+		t.Error("pausing left the timer running")
+	}
+	d.mu.Unlock()
+
+	// Paused time doesn't count down
+	time.Sleep(50 * time.Millisecond)
+	d.mu.Lock()
+	if remaining := d.ClockRemaining(); remaining != paused {
+		t.Errorf("%v left after a pause, want the %v left when it was paused", remaining, paused)
+	}
+	d.mu.Unlock()
+
+	// Running out while paused doesn't pick
+	d.mu.Lock()
+	d.Clock.Remaining = 20 * time.Millisecond
+	d.mu.Unlock()
+	assertPicks(t, d, 0)
+
+	// Resuming picks up from the time left
+	d.mu.Lock()
+	d.ResumeClock()
+	drafts.scheduleAutoPick(d)
+	if remaining := d.ClockRemaining(); remaining > 20*time.Millisecond {
+		t.Errorf("%v left after resuming, want at most the 20ms left at the pause", remaining)
+	}
+	d.mu.Unlock()
+	waitForPicks(t, d, 1)
+}
+
+func TestClockStops(t *testing.T) {
+	tests := []struct {
+		name      string
+		stop      func(t *testing.T, d *Draft)
+		wantPicks int
+		fullTurn  bool // Whether a fresh turn is running afterwards
+	}{
+		{
+			name:      "undo",
+			wantPicks: 0,
+			fullTurn:  true,
+			stop: func(t *testing.T, d *Draft) {
+				d.mu.Lock()
+				defer d.mu.Unlock()
+				if _, err := d.UndoLastPick(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				drafts.startPickClock(d)
+			},
+		},
+		{
+			name:      "close",
+			wantPicks: 1,
+			stop: func(t *testing.T, d *Draft) {
+				if err := drafts.Remove(d.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := clockDraft(t)
+
+			// One pick in, the second captain's turn is about to run out
+			d.mu.Lock()
+			if _, err := d.PickPlayer(context.Background(), 3); err != nil {
+				t.Fatal(err)
+			}
+			d.mu.Unlock()
+			runOutSoon(d)
+
+			tt.stop(t, d)
+
+			// The turn that was running out never auto-picks
+			assertPicks(t, d, tt.wantPicks)
+			if tt.fullTurn {
+				d.mu.Lock()
+				if remaining := d.ClockRemaining(); remaining <= 4*time.Second {
+					t.Errorf("the turn after the undo has %v left, want a full turn", remaining)
+				}
+				d.mu.Unlock()
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Draft holds everything about one draft session: the tournament being drafted, its captains, the pick order and the remaining pool. Each session is independent, so several organizers can run drafts at once.
+type Draft struct {
+	ID                 string
+	OrganizerKey       string // Secret that signs a browser in as the organizer, see organizer.go
+	CreatedAt          time.Time
+	Scene              string // HiveMind scene the draft is for, which decides its branding. "" is the default scene.
+	SelectedTournament []string
+	TournamentID       string
+	Source             string     // Where the players came from: "hivemind" (or "" for older drafts), "csv" or "json"
+	LocalTeams         []TeamInfo // Teams for imported drafts, which don't have a HiveMind tournament to write to
+	FormFields         []FormField
+	BoardFields        []string // Slugs of the fields shown on player cards, nil shows them all
+	Players            []Player
+	Captains           []Captain
+	DraftOrder         []Captain
+	UnassignedCaptains []Captain
+	DraftPlayers       []Player
+	Mode               string
+	Auction            *Auction
+	Proposal           *BalanceProposal
+	Clock              *PickClock
+	Queues             map[string][]float64 // Auto-pick queues of player IDs, keyed by captain ID
+	CaptainLinks       []CaptainLink
+	Format             string
+	CustomSequence     string
+	Sequence           []Slot
+	RosterRules        []RosterRule
+	RosterEnforcement  string // "warn" (or "" for older drafts) or "block"
+	MixItUp            bool   // Steer auto-picks and balancing away from recent teammates
+	CoPlay             *CoPlayHistory
+	UseRatings         bool // Rank, balance and auto-pick by HiveMind rating instead of self-reported skill
+	Teams              []TeamInfo
+	Picks              []Pick
+	History            []HistoryEntry
+
+	mu     sync.Mutex
+	timer  *time.Timer
+	events *EventHub
+}
+
+// Pick records one player being drafted, along with whose turn it was so the pick can be undone
+type Pick struct {
+	Number       int
+	CaptainID    float64
+	CaptainName  string
+	CaptainIndex int
+	Round        int
+	PlayerID     float64
+	PlayerName   string
+	TeamID       int
+	Price        int  // Points paid in an auction draft
+	Auto         bool // Made by the pick clock when the captain ran out of time
+	At           time.Time
+}
+
+// HistoryEntry is one line of the draft log. Undone picks stay in the history with an "undo" entry after them.
+type HistoryEntry struct {
+	Action string // "pick" or "undo"
+	Pick   Pick
+	At     time.Time
+}
+
+// TournamentName returns the name of the draft's tournament, or "" if none has been selected
+func (d *Draft) TournamentName() string {
+	if len(d.SelectedTournament) < 2 {
+		return ""
+	}
+	return d.SelectedTournament[1]
+}
+
+// Branding returns the look of the draft's scene
+func (d *Draft) Branding() Branding {
+	return BrandingFor(d.Scene)
+}
+
+// Stage describes how far along the draft is, for the lobby
+func (d *Draft) Stage() string {
+	switch {
+	case d.Finished():
+		return "Done"
+	case d.Mode == autoMode:
+		return "Reviewing balanced teams"
+	case len(d.DraftOrder) == 0:
+		return "Selecting captains"
+	case len(d.UnassignedCaptains) > 0:
+		return "Building teams"
+	case d.Mode == auctionMode:
+		return "Auction"
+	default:
+		return "Drafting"
+	}
+}
+
+// SetFormat picks the draft format and lays out every pick for the current captains and pool. The format can only change before the first pick.
+func (d *Draft) SetFormat(name, custom string) error {
+	if len(d.Picks) > 0 {
+		return fmt.Errorf("the draft format can't change once picks have been made")
+	}
+
+	format, err := DraftFormatByName(name, custom)
+	if err != nil {
+		return err
+	}
+	if customFormat, ok := format.(CustomFormat); ok {
+		if err := customFormat.Validate(len(d.DraftOrder)); err != nil {
+			return err
+		}
+		custom = customFormat.String()
+	} else {
+		custom = ""
+	}
+
+	d.Format = format.Name()
+	d.CustomSequence = custom
+	d.Sequence = format.Sequence(len(d.DraftOrder), len(d.DraftPlayers))
+	return nil
+}
+
+// FormatLabel returns the display name of the draft's format
+func (d *Draft) FormatLabel() string {
+	format, err := DraftFormatByName(d.Format, d.CustomSequence)
+	if err != nil {
+		return d.Format
+	}
+	return format.Label()
+}
+
+// sequence returns the draft's picks in order. Drafts saved before formats existed, or whose pool has grown, get a fresh sequence, which is only kept once CurrentSlot lays it out.
+func (d *Draft) sequence() []Slot {
+	if len(d.Sequence) >= len(d.Picks)+len(d.DraftPlayers) {
+		return d.Sequence
+	}
+	format, err := DraftFormatByName(d.Format, d.CustomSequence)
+	if err != nil {
+		format = SnakeFormat{}
+	}
+	return format.Sequence(len(d.DraftOrder), len(d.Picks)+len(d.DraftPlayers))
+}
+
+// CurrentSlot returns the pick that's up next, laying out a fresh sequence if the draft needs one
+func (d *Draft) CurrentSlot() Slot {
+	d.Sequence = d.sequence()
+	return d.peekSlot()
+}
+
+// peekSlot returns the pick that's up next without changing the draft, for pages and snapshots that only read it
+func (d *Draft) peekSlot() Slot {
+	sequence := d.sequence()
+	if len(d.Picks) >= len(sequence) {
+		return Slot{}
+	}
+	return sequence[len(d.Picks)]
+}
+
+// CurrentCaptain returns the captain whose turn it is
+func (d *Draft) CurrentCaptain() Captain {
+	return d.slotCaptain(d.CurrentSlot())
+}
+
+// peekCaptain returns the captain whose turn it is without changing the draft
+func (d *Draft) peekCaptain() Captain {
+	return d.slotCaptain(d.peekSlot())
+}
+
+// slotCaptain returns the captain who picks in a slot
+func (d *Draft) slotCaptain(slot Slot) Captain {
+	if slot.CaptainIndex >= len(d.DraftOrder) {
+		return Captain{}
+	}
+	return d.DraftOrder[slot.CaptainIndex]
+}
+
+// pageData returns the values every draft template expects, plus any extras for a specific page
+func (d *Draft) pageData(extra gin.H) gin.H {
+	data := gin.H{
+		"draftID":              d.ID,
+		"branding":             d.Branding(),
+		"selectedTournament":   d.SelectedTournament,
+		"formFields":           d.FormFields,
+		"boardFields":          d.boardFields(),
+		"shownFields":          d.shownFields(),
+		"playerCount":          len(d.Players),
+		"players":              d.Players,
+		"captainCount":         len(d.Captains),
+		"remainingPlayerCount": len(d.DraftPlayers),
+		"unassignedCaptains":   d.UnassignedCaptains,
+		"draftOrder":           d.DraftOrder,
+		"draftPlayers":         d.DraftPlayers,
+		"currentCaptain":       d.peekCaptain().Name,
+		"currentSlot":          d.peekSlot(),
+		"formatLabel":          d.FormatLabel(),
+		"mode":                 d.Mode,
+		"teams":                d.Teams,
+		"picks":                d.Picks,
+		"history":              d.History,
+		"clock":                d.Clock,
+		"clockSeconds":         int(d.ClockRemaining().Seconds()),
+		"queues":               d.CaptainQueues(),
+		"rosterRules":          d.RosterRulesText(),
+		"rosterEnforcement":    d.RosterEnforcement,
+		"pairRequests":         d.PairRequests(),
+		"pairNotes":            d.pairNotes(),
+		"mixItUp":              d.MixItUp,
+		"coPlay":               d.coPlay(),
+		"recentTeammates":      d.recentTeammates(d.captainRoster(d.peekCaptain())),
+		"sceneRatings":         d.sceneRatings(),
+		"useRatings":           d.UseRatings,
+		"playerRatings":        d.ratingNotes(),
+		"lastEventID":          d.lastEventID(),
+	}
+	data["ratingsRefreshing"], data["ratingsError"] = drafts.RatingsRefresh(d.sceneName())
+	for k, v := range extra {
+		data[k] = v
+	}
+	return data
+}
+
+// lastEventID is the event the page was rendered at, so its event stream picks up from there
+func (d *Draft) lastEventID() int {
+	if d.events == nil {
+		return 0
+	}
+	return d.events.LastID()
+}
+
+// Finished reports whether every player has been drafted, or placed on a balanced team
+func (d *Draft) Finished() bool {
+	return (len(d.DraftOrder) > 0 || d.Mode == autoMode) && len(d.DraftPlayers) == 0
+}
+
+// DraftRegistry keeps track of the active draft sessions by ID and saves them to its store
+type DraftRegistry struct {
+	mu      sync.RWMutex
+	drafts  map[string]*Draft
+	codes   map[string]string // Captain short codes to the ID of their draft
+	store   DraftStore
+	pairs   pairBook
+	ratings ratingBook
+}
+
+func NewDraftRegistry(store DraftStore) *DraftRegistry {
+	if store == nil {
+		store = newMemoryStore()
+	}
+	return &DraftRegistry{drafts: make(map[string]*Draft), codes: make(map[string]string), store: store}
+}
+
+// Restore loads every unfinished draft from the store so it can be resumed where it left off
+func (r *DraftRegistry) Restore() (restored int, err error) {
+	loaded, err := r.store.LoadAll()
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range loaded {
+		if d.Finished() {
+			continue
+		}
+		d.events = NewEventHub()
+		ensureOrganizerKey(d)
+		r.drafts[d.ID] = d
+		for _, link := range d.CaptainLinks {
+			r.codes[link.Code] = d.ID
+		}
+		restored++
+
+		// Pick the auction countdown or pick clock back up where it left off
+		d.mu.Lock()
+		r.scheduleLotClose(d)
+		r.scheduleAutoPick(d)
+		d.mu.Unlock()
+	}
+
+	return restored, nil
+}
+
+// Save persists a draft. Callers must hold the draft's lock.
+func (r *DraftRegistry) Save(d *Draft) error {
+	return r.store.Save(d)
+}
+
+// Create starts a new, empty draft session and registers it
+func (r *DraftRegistry) Create() *Draft {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := &Draft{
+		ID:           newDraftID(),
+		OrganizerKey: randomToken(16),
+		CreatedAt:    time.Now(),
+		Mode:         turnsMode,
+		Format:       SnakeFormat{}.Name(),
+		events:       NewEventHub(),
+	}
+	for r.drafts[d.ID] != nil {
+		d.ID = newDraftID()
+	}
+	r.drafts[d.ID] = d
+
+	return d
+}
+
+// Start loads the players from source and registers a new draft for them in the given scene
+func (r *DraftRegistry) Start(ctx context.Context, source PlayerSource, scene string, selectedTournament []string, tournamentID string) (*Draft, error) {
+	formFields, players, err := source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("# of players: %v", len(players))
+
+	d := r.Create()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Scene = normalizeScene(scene)
+	d.SelectedTournament = selectedTournament
+	d.TournamentID = tournamentID
+	d.Source = source.Name()
+	d.FormFields = formFields
+	d.Players = players
+	log.Printf("Started draft %v for %v from %v", d.ID, d.TournamentName(), d.Source)
+
+	if err := r.Save(d); err != nil {
+		log.Printf("Failed to save draft %v: %v", d.ID, err)
+	}
+	return d, nil
+}
+
+// Get returns the draft with the given ID, or nil if there isn't one
+func (r *DraftRegistry) Get(id string) *Draft {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.drafts[id]
+}
+
+// Remove forgets a draft session and deletes it from the store
+func (r *DraftRegistry) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if d := r.drafts[id]; d != nil {
+		d.stopTimer()
+		for _, link := range d.CaptainLinks {
+			delete(r.codes, link.Code)
+		}
+	}
+	delete(r.drafts, id)
+	return r.store.Delete(id)
+}
+
+// schedule runs fn with the draft locked at the given time, replacing any timer already set for the draft. Callers must hold the draft's lock.
+func (r *DraftRegistry) schedule(d *Draft, at time.Time, fn func(d *Draft)) {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+
+	d.timer = time.AfterFunc(time.Until(at), func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		// The draft may have been closed while the timer was waiting
+		if r.Get(d.ID) != d {
+			return
+		}
+
+		fn(d)
+		if err := r.Save(d); err != nil {
+			log.Printf("Failed to save draft %v: %v", d.ID, err)
+		}
+	})
+}
+
+// List returns every active draft, newest first
+func (r *DraftRegistry) List() (drafts []*Draft) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, d := range r.drafts {
+		drafts = append(drafts, d)
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].CreatedAt.After(drafts[j].CreatedAt)
+	})
+
+	return drafts
+}
+
+// newDraftID returns a short random ID that's easy to read out loud or type into a phone
+func newDraftID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("150405.000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,43 +1,233 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
+	"time"
 )
 
-func advanceDraftTurn(draftOrder []Captain) {
-	// Move to the next captain
-	currentCaptainIndex += draftDirection
+func RemoveDraftedPlayers(draftPlayers []Player, selectedPlayer float64) (updatedDraftPlayers []Player) {
+	for _, player := range draftPlayers {
+		if player.ID != selectedPlayer {
+			updatedDraftPlayers = append(updatedDraftPlayers, player)
+		}
+	}
+
+	return updatedDraftPlayers
+}
 
-	// If we reach the end of the list (ascending), reverse direction and repeat last captain
-	if currentCaptainIndex >= len(draftOrder) {
-		currentCaptainIndex = len(draftOrder) - 1
-		draftDirection = -1
-	} else if currentCaptainIndex < 0 { // If we reach the start (descending), reverse direction again
-		currentCaptainIndex = 0
-		draftDirection = 1
+// AddPlayerToDraftTeam writes a pick to the team store (HiveMind, unless the players were imported) by moving the drafted player onto the current captain's team. It returns the team the player was added to.
+func AddPlayerToDraftTeam(ctx context.Context, store TeamStore, teams []TeamInfo, captain Captain, draftedPlayer Player) (teamID int, err error) {
+	teamID = CaptainTeamID(teams, captain)
+	if teamID == 0 {
+		return 0, fmt.Errorf("%v hasn't been assigned to a team yet, assign them on the teams page before picking", captain.Name)
 	}
+
+	if err := store.AssignPlayer(ctx, formatID(draftedPlayer.ID), strconv.Itoa(teamID)); err != nil {
+		return 0, fmt.Errorf("adding %v to %v's team: %w", draftedPlayer.Name, captain.Name, err)
+	}
+
+	return teamID, nil
 }
 
-func RemoveDraftedPlayers(draftPlayers []Player, selectedPlayer string) (updatedDraftPlayers []Player) {
-	for _, player := range draftPlayers {
-		if player.Name != selectedPlayer {
-			updatedDraftPlayers = append(updatedDraftPlayers, player)
+// CaptainTeamID finds the team a captain leads. The team saved when the captain was assigned wins, otherwise we look for the team the captain is playing on.
+func CaptainTeamID(teams []TeamInfo, captain Captain) int {
+	for _, team := range teams {
+		if captain.TeamID != 0 && team.ID == captain.TeamID {
+			return team.ID
 		}
 	}
 
-	return updatedDraftPlayers
+	for _, team := range teams {
+		for _, player := range team.Players {
+			if player.ID == captain.ID {
+				return team.ID
+			}
+		}
+	}
+
+	return 0
 }
 
-func AddPlayerToDraftTeam(tournamentID string, teams []TeamInfo, captain string, draftedPlayer string) {
-	api := fmt.Sprintf("https://kqhivemind.com/api/tournament/player/?tournament_id=%v&format=json", tournamentID)
+// setPlayerTeam keeps the draft's copy of a player in sync with HiveMind after their team changes
+func (d *Draft) setPlayerTeam(playerID float64, teamID int) {
+	for i := range d.Players {
+		if d.Players[i].ID == playerID {
+			d.Players[i].Team = teamID
+		}
+	}
+}
 
-	client, req := createRequest("PATCH", api, nil)
-	resp, err := client.Do(req)
+// setCaptainTeam remembers which team a captain leads
+func (d *Draft) setCaptainTeam(captainID float64, teamID int) {
+	for _, list := range [][]Captain{d.Captains, d.DraftOrder, d.UnassignedCaptains} {
+		for i := range list {
+			if list[i].ID == captainID {
+				list[i].TeamID = teamID
+			}
+		}
+	}
+	d.setPlayerTeam(captainID, teamID)
+}
+
+// errAlreadyDrafted is returned when a pick names a player who isn't in the pool any more
+var errAlreadyDrafted = errors.New("that player has already been drafted")
+
+// poolPlayer returns the player with the given ID if they're still available
+func (d *Draft) poolPlayer(playerID float64) (Player, bool) {
+	for _, player := range d.DraftPlayers {
+		if player.ID == playerID {
+			return player, true
+		}
+	}
+	return Player{}, false
+}
+
+// PickPlayer makes the current captain's pick: the player is written to the captain's HiveMind team, then removed from the pool and logged
+func (d *Draft) PickPlayer(ctx context.Context, playerID float64) (Pick, error) {
+	player, ok := d.poolPlayer(playerID)
+	if !ok {
+		return Pick{}, errAlreadyDrafted
+	}
+	captain := d.CurrentCaptain()
+
+	teamID, err := AddPlayerToDraftTeam(ctx, d.teamStore(), d.Teams, captain, player)
 	if err != nil {
-		log.Fatal(err)
+		return Pick{}, err
 	}
-	defer resp.Body.Close()
+	log.Printf("Draft %v: %v picked %v for team %v", d.ID, captain.Name, player.Name, teamID)
+
+	// Record the pick, remove the player from the pool and move on to the next slot
+	pick := d.recordPick(player, captain, teamID, 0)
+	d.refreshTeams(ctx)
+
+	return pick, nil
+}
 
-	
-}
\ No newline at end of file
+// refreshTeams reloads the rosters after they change and tells every page. The change already went through, so a failure here only means a stale roster until the next page load.
+func (d *Draft) refreshTeams(ctx context.Context) {
+	if teams, err := d.teamStore().Teams(ctx, d.Players); err != nil {
+		log.Printf("Draft %v: refreshing teams: %v", d.ID, err)
+	} else {
+		d.Teams = teams
+	}
+	d.publishTeams()
+}
+
+// recordPick removes a player from the pool and logs the pick, which moves the draft on to the next slot
+func (d *Draft) recordPick(player Player, captain Captain, teamID int, price int) Pick {
+	slot := d.CurrentSlot()
+	if d.Mode == auctionMode {
+		// Auction picks don't follow a sequence, so the round is just how many players each captain has bought so far
+		slot = Slot{Round: len(d.Picks)/len(d.DraftOrder) + 1, CaptainIndex: d.captainIndex(captain.ID)}
+	}
+
+	pick := Pick{
+		Number:       len(d.Picks) + 1,
+		CaptainID:    captain.ID,
+		CaptainName:  captain.Name,
+		CaptainIndex: slot.CaptainIndex,
+		Round:        slot.Round,
+		PlayerID:     player.ID,
+		PlayerName:   player.Name,
+		TeamID:       teamID,
+		Price:        price,
+		At:           time.Now(),
+	}
+
+	d.Picks = append(d.Picks, pick)
+	d.History = append(d.History, HistoryEntry{Action: "pick", Pick: pick, At: pick.At})
+
+	// Remove the selected player from the list
+	d.setPlayerTeam(player.ID, teamID)
+	d.DraftPlayers = RemoveDraftedPlayers(d.DraftPlayers, player.ID)
+
+	d.publish("pick", pick)
+	d.publishTurn()
+
+	return pick
+}
+
+// UndoLastPick takes back the most recent pick: the player's team is cleared in HiveMind, they go back into the pool and the turn goes back to the captain who made the pick
+func (d *Draft) UndoLastPick(ctx context.Context) (Pick, error) {
+	if len(d.Picks) == 0 {
+		return Pick{}, fmt.Errorf("there are no picks to undo")
+	}
+	pick := d.Picks[len(d.Picks)-1]
+
+	// Clear the player's team first so a failure leaves the draft untouched
+	if err := d.teamStore().AssignPlayer(ctx, formatID(pick.PlayerID), ""); err != nil {
+		return Pick{}, fmt.Errorf("undoing pick %d (%v): %w", pick.Number, pick.PlayerName, err)
+	}
+
+	d.Picks = d.Picks[:len(d.Picks)-1]
+	d.History = append(d.History, HistoryEntry{Action: "undo", Pick: pick, At: time.Now()})
+
+	// Auction picks get their points back, and the nomination goes back to whoever's turn it was
+	if pick.Price > 0 && d.Auction != nil {
+		d.Auction.Budgets[formatID(pick.CaptainID)] += pick.Price
+		if len(d.DraftOrder) > 0 {
+			d.Auction.NominationIndex = (d.Auction.NominationIndex - 1 + len(d.DraftOrder)) % len(d.DraftOrder)
+		}
+	}
+
+	// Put the player back in the pool, in their original registration order
+	d.setPlayerTeam(pick.PlayerID, 0)
+	for _, player := range d.Players {
+		if player.ID == pick.PlayerID {
+			player.Team = 0
+			d.DraftPlayers = append(d.DraftPlayers, player)
+			break
+		}
+	}
+	d.sortDraftPlayers()
+
+	d.publish("undo", pick)
+	d.publishTurn()
+
+	log.Printf("Draft %v: undid pick %d (%v to %v)", d.ID, pick.Number, pick.PlayerName, pick.CaptainName)
+	return pick, nil
+}
+
+// UndoToPick undoes picks from the most recent back to pick number n, so pick n is the next one to be made
+func (d *Draft) UndoToPick(ctx context.Context, n int) (undone []Pick, err error) {
+	if n < 1 || n > len(d.Picks) {
+		return nil, fmt.Errorf("pick %d doesn't exist, there have been %d picks", n, len(d.Picks))
+	}
+
+	for len(d.Picks) >= n {
+		pick, err := d.UndoLastPick(ctx)
+		if err != nil {
+			return undone, err
+		}
+		undone = append(undone, pick)
+	}
+
+	return undone, nil
+}
+
+// sortDraftPlayers keeps the pool in the same order players appear in the tournament's player list
+func (d *Draft) sortDraftPlayers() {
+	position := make(map[float64]int, len(d.Players))
+	for i, player := range d.Players {
+		position[player.ID] = i
+	}
+
+	sort.SliceStable(d.DraftPlayers, func(i, j int) bool {
+		return position[d.DraftPlayers[i].ID] < position[d.DraftPlayers[j].ID]
+	})
+}
+
+// captainIndex returns a captain's position in the draft order, or -1
+func (d *Draft) captainIndex(captainID float64) int {
+	for i, captain := range d.DraftOrder {
+		if captain.ID == captainID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// localDraft builds an imported draft with teams kept in the draft, so tests don't need HiveMind. The first captains players lead a team each, in draft order, and skills run 1 to 5.
+func localDraft(t *testing.T, players, captains int) *Draft {
+	t.Helper()
+	ctx := context.Background()
+
+	d := &Draft{ID: "test", Source: csvSource, Mode: turnsMode, events: NewEventHub()}
+	for i := 1; i <= players; i++ {
+		d.Players = append(d.Players, Player{
+			ID:         float64(i),
+			Name:       fmt.Sprintf("Player %d", i),
+			FormFields: map[string]FieldValue{"skill": NumberValue(float64((i-1)%5 + 1))},
+		})
+	}
+	for i := 0; i < captains; i++ {
+		d.Captains = append(d.Captains, Captain{ID: d.Players[i].ID, Name: d.Players[i].Name, Order: i + 1})
+	}
+	d.DraftOrder = append([]Captain(nil), d.Captains...)
+	d.DraftPlayers = RemoveCaptainsFromPlayers(d.Players, d.Captains)
+
+	store := d.teamStore()
+	for _, captain := range d.DraftOrder {
+		teamID, err := store.AddTeam(ctx, captain.Name+"'s Team")
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.setCaptainTeam(captain.ID, teamID)
+	}
+	if err := d.SetFormat(SnakeFormat{}.Name(), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	if d.Teams, err = store.Teams(ctx, d.Players); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// playerIDs lists the IDs of players in order
+func playerIDs(players []Player) []float64 {
+	ids := make([]float64, len(players))
+	for i, player := range players {
+		ids[i] = player.ID
+	}
+	return ids
+}
+
+func TestUndoToPick(t *testing.T) {
+	tests := []struct {
+		name        string
+		picks       []float64 // Players picked in order, captains 1 and 2 picking snake order
+		undoTo      int
+		wantPicks   int
+		wantCaptain float64
+	}{
+		{name: "last pick", picks: []float64{3, 4, 5}, undoTo: 3, wantPicks: 2, wantCaptain: 2},
+		{name: "back to the start", picks: []float64{3, 4, 5}, undoTo: 1, wantPicks: 0, wantCaptain: 1},
+		{name: "into the previous round", picks: []float64{6, 3, 5, 4}, undoTo: 2, wantPicks: 1, wantCaptain: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			d := localDraft(t, 8, 2)
+			pool := playerIDs(d.DraftPlayers)
+
+			for _, id := range tt.picks {
+				if _, err := d.PickPlayer(ctx, id); err != nil {
+					t.Fatalf("picking %v: %v", id, err)
+				}
+			}
+
+			undone, err := d.UndoToPick(ctx, tt.undoTo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(undone) != len(tt.picks)-tt.wantPicks || len(d.Picks) != tt.wantPicks {
+				t.Fatalf("undid %d picks leaving %d, want %d left", len(undone), len(d.Picks), tt.wantPicks)
+			}
+			if captain := d.CurrentCaptain(); captain.ID != tt.wantCaptain {
+				t.Errorf("%v is up, want captain %v", captain.Name, tt.wantCaptain)
+			}
+
+			// Undone players are off their teams and back in the pool in registration order
+			for _, pick := range undone {
+				for _, player := range d.Players {
+					if player.ID == pick.PlayerID && player.Team != 0 {
+						t.Errorf("%v is still on team %v", player.Name, player.Team)
+					}
+				}
+			}
+			want := pool
+			for _, pick := range d.Picks {
+				want = removeID(want, pick.PlayerID)
+			}
+			if got := playerIDs(d.DraftPlayers); !reflect.DeepEqual(got, want) {
+				t.Errorf("pool is %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestUndoWithoutPicks(t *testing.T) {
+	d := localDraft(t, 4, 2)
+	if _, err := d.UndoLastPick(context.Background()); err == nil {
+		t.Error("undoing with no picks didn't return an error")
+	}
+	if _, err := d.UndoToPick(context.Background(), 1); err == nil {
+		t.Error("undoing to a pick that doesn't exist didn't return an error")
+	}
+}
+
+// removeID drops one ID from a list
+func removeID(ids []float64, id float64) (kept []float64) {
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+	return kept
+}
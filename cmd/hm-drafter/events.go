@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// eventBacklog is how many recent events each draft keeps for clients that reconnect
+const eventBacklog = 256
+
+// eventKeepAlive is how often an idle stream gets a comment so proxies (and Heroku's router) don't close it
+const eventKeepAlive = 20 * time.Second
+
+// DraftEvent is one update on a draft's event stream. IDs count up from 1 for each draft.
+type DraftEvent struct {
+	ID   int
+	Type string // "pick", "undo", "turn", "clock", "auction" or "teams"
+	Data interface{}
+}
+
+// EventHub fans a draft's events out to every connected page and remembers the recent ones so a reconnecting page can catch up
+type EventHub struct {
+	mu          sync.Mutex
+	lastID      int
+	backlog     []DraftEvent
+	subscribers map[chan DraftEvent]bool
+}
+
+func NewEventHub() *EventHub {
+	return &EventHub{subscribers: make(map[chan DraftEvent]bool)}
+}
+
+// Publish sends an event to every subscriber. A subscriber that can't keep up is dropped, its page reconnects and replays what it missed.
+func (h *EventHub) Publish(eventType string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := DraftEvent{ID: h.lastID, Type: eventType, Data: data}
+
+	h.backlog = append(h.backlog, event)
+	if len(h.backlog) > eventBacklog {
+		h.backlog = h.backlog[len(h.backlog)-eventBacklog:]
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// LastID returns the ID of the most recent event, so pages can subscribe from the point they were rendered
+func (h *EventHub) LastID() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lastID
+}
+
+// Subscribe starts a stream of events after lastID, returning the ones already missed. It returns ok=false if the missed events are no longer in the backlog (or came from before a restart), in which case the page has to reload.
+func (h *EventHub) Subscribe(lastID int) (missed []DraftEvent, ch chan DraftEvent, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ok = lastID <= h.lastID
+	if len(h.backlog) > 0 && lastID < h.backlog[0].ID-1 {
+		ok = false
+	}
+	if ok {
+		for _, event := range h.backlog {
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	ch = make(chan DraftEvent, 64)
+	h.subscribers[ch] = true
+	return missed, ch, ok
+}
+
+// Unsubscribe stops sending events to ch
+func (h *EventHub) Unsubscribe(ch chan DraftEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[ch] {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+// publish sends an event to the draft's connected pages. Drafts that aren't registered yet have no hub and skip it.
+func (d *Draft) publish(eventType string, data interface{}) {
+	if d.events != nil {
+		d.events.Publish(eventType, data)
+	}
+}
+
+// publishTurn tells pages whose turn it is now
+func (d *Draft) publishTurn() {
+	d.publish("turn", gin.H{
+		"captain":   d.CurrentCaptain().Name,
+		"slot":      d.CurrentSlot(),
+		"remaining": len(d.DraftPlayers),
+		"finished":  d.Finished(),
+	})
+}
+
+// publishClock tells pages how long the current captain has left
+func (d *Draft) publishClock() {
+	if d.Clock == nil {
+		return
+	}
+	d.publish("clock", gin.H{
+		"seconds": int(d.ClockRemaining().Seconds()),
+		"paused":  d.Clock.Paused,
+	})
+}
+
+// publishTeams tells pages the rosters changed
+func (d *Draft) publishTeams() {
+	d.publish("teams", gin.H{
+		"teams":              spectatorTeams(d.Teams),
+		"unassignedCaptains": len(d.UnassignedCaptains),
+	})
+}
+
+// streamEvents serves a draft's event stream. It sits outside loadDraft since the stream stays open for as long as the page does and mustn't hold the draft's lock.
+func streamEvents(c *gin.Context) {
+	d := drafts.Get(c.Param("id"))
+	if d == nil {
+		c.String(http.StatusNotFound, "Draft not found")
+		return
+	}
+
+	// Browsers send Last-Event-ID when they reconnect, pages send the ID they were rendered at on their first connection
+	lastID, err := strconv.Atoi(c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		lastID, err = strconv.Atoi(c.Query("lastEventID"))
+	}
+	if err != nil {
+		lastID = d.events.LastID()
+	}
+
+	missed, ch, ok := d.events.Subscribe(lastID)
+	defer d.events.Unsubscribe(ch)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("X-Accel-Buffering", "no")
+
+	if !ok {
+		log.Printf("Draft %v: event %v is too old to replay, telling the page to reload", d.ID, lastID)
+		c.Render(-1, sse.Event{Event: "reload", Data: gin.H{}})
+		c.Writer.Flush()
+		return
+	}
+	for _, event := range missed {
+		c.Render(-1, sse.Event{Id: strconv.Itoa(event.ID), Event: event.Type, Data: event.Data})
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, open := <-ch:
+			if !open {
+				return false
+			}
+			c.Render(-1, sse.Event{Id: strconv.Itoa(event.ID), Event: event.Type, Data: event.Data})
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
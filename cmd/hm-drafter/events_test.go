@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// publishN publishes n events to the hub, numbered from 1
+func publishN(h *EventHub, n int) {
+	for i := 0; i < n; i++ {
+		h.Publish("pick", i+1)
+	}
+}
+
+// eventIDs lists the IDs of events in order
+func eventIDs(events []DraftEvent) (ids []int) {
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+// idRange is the IDs from first to last
+func idRange(first, last int) (ids []int) {
+	for id := first; id <= last; id++ {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestSubscribeReplay(t *testing.T) {
+	trimmed := eventBacklog + 44 // The backlog starts at event 45
+
+	tests := []struct {
+		name       string
+		published  int
+		lastID     int
+		wantMissed []int
+		wantOK     bool
+	}{
+		{name: "caught up", published: 5, lastID: 5, wantOK: true},
+		{name: "missed a few", published: 5, lastID: 2, wantMissed: []int{3, 4, 5}, wantOK: true},
+		{name: "from the start", published: 5, lastID: 0, wantMissed: idRange(1, 5), wantOK: true},
+		{name: "nothing published yet", published: 0, lastID: 0, wantOK: true},
+		{name: "ID from before a restart", published: 5, lastID: 9, wantOK: false},
+		{name: "just inside the trimmed backlog", published: trimmed, lastID: 44, wantMissed: idRange(45, trimmed), wantOK: true},
+		{name: "older than the trimmed backlog", published: trimmed, lastID: 43, wantOK: false},
+		{name: "caught up after trimming", published: trimmed, lastID: trimmed, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewEventHub()
+			publishN(h, tt.published)
+
+			missed, ch, ok := h.Subscribe(tt.lastID)
+			defer h.Unsubscribe(ch)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok %v, want %v", ok, tt.wantOK)
+			}
+			if got := eventIDs(missed); !reflect.DeepEqual(got, tt.wantMissed) {
+				t.Errorf("replayed %v, want %v", got, tt.wantMissed)
+			}
+
+			// Whatever was replayed, new events still arrive
+			h.Publish("turn", nil)
+			if event := <-ch; event.ID != tt.published+1 || event.Type != "turn" {
+				t.Errorf("got event %+v next, want turn %v", event, tt.published+1)
+			}
+		})
+	}
+}
+
+func TestBacklogTrim(t *testing.T) {
+	tests := []struct {
+		published int
+		wantFirst int
+		wantLen   int
+	}{
+		{published: 1, wantFirst: 1, wantLen: 1},
+		{published: eventBacklog, wantFirst: 1, wantLen: eventBacklog},
+		{published: eventBacklog + 1, wantFirst: 2, wantLen: eventBacklog},
+		{published: 3 * eventBacklog, wantFirst: 2*eventBacklog + 1, wantLen: eventBacklog},
+	}
+
+	for _, tt := range tests {
+		h := NewEventHub()
+		publishN(h, tt.published)
+
+		if len(h.backlog) != tt.wantLen || h.backlog[0].ID != tt.wantFirst {
+			t.Errorf("after %v events the backlog has %v starting at %v, want %v starting at %v", tt.published, len(h.backlog), h.backlog[0].ID, tt.wantLen, tt.wantFirst)
+		}
+		if h.LastID() != tt.published {
+			t.Errorf("after %v events the last ID is %v", tt.published, h.LastID())
+		}
+	}
+}
+
+func TestSubscriberCleanup(t *testing.T) {
+	h := NewEventHub()
+	_, kept, _ := h.Subscribe(0)
+	_, left, _ := h.Subscribe(0)
+	_, slow, _ := h.Subscribe(0)
+
+	// Unsubscribing closes the channel, and doing it twice is harmless
+	h.Unsubscribe(left)
+	h.Unsubscribe(left)
+	if _, open := <-left; open {
+		t.Error("an unsubscribed channel is still open")
+	}
+
+	// A subscriber that never reads is dropped once its buffer is full, the others keep going
+	for i := 0; i < cap(slow)+1; i++ {
+		h.Publish("pick", i)
+		<-kept
+	}
+	h.mu.Lock()
+	subscribers := len(h.subscribers)
+	_, slowSubscribed := h.subscribers[slow]
+	h.mu.Unlock()
+	if subscribers != 1 || slowSubscribed {
+		t.Errorf("got %v subscribers after one left and one fell behind, want 1", subscribers)
+	}
+
+	received := 0
+	for range slow {
+		received++
+	}
+	if received != cap(slow) {
+		t.Errorf("the slow subscriber got %v events before it was dropped, want %v", received, cap(slow))
+	}
+
+	// Unsubscribing a dropped subscriber is harmless too
+	h.Unsubscribe(slow)
+	h.Unsubscribe(kept)
+	if len(h.subscribers) != 0 {
+		t.Errorf("%v subscribers left after everyone unsubscribed", len(h.subscribers))
+	}
+}
+
+// readEvents reads a stream until it has seen want events or the stream ends, returning their IDs and types
+func readEvents(t *testing.T, resp *http.Response, want int) (ids, types []string) {
+	t.Helper()
+	id, eventType := "", ""
+	scanner := bufio.NewScanner(resp.Body)
+	for len(types) < want && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"):
+			eventType = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case line == "" && eventType != "":
+			if id != "" {
+				ids = append(ids, id)
+			}
+			types = append(types, eventType)
+			id, eventType = "", ""
+		}
+	}
+	return ids, types
+}
+
+func TestStreamEventsReplay(t *testing.T) {
+	startFakeHiveMind(t)
+	d := drafts.Create()
+	publishN(d.events, 5)
+	server := httptest.NewServer(setupRouter())
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		lastEventID string // Sent as the Last-Event-ID header, like a browser reconnecting
+		query       string
+		wantIDs     []string
+		wantTypes   []string
+	}{
+		{name: "reconnecting replays what was missed", lastEventID: "3", wantIDs: []string{"4", "5"}, wantTypes: []string{"pick", "pick"}},
+		{name: "first connection from a rendered page", query: "?lastEventID=4", wantIDs: []string{"5"}, wantTypes: []string{"pick"}},
+		{name: "an ID from before a restart reloads the page", lastEventID: "99", wantTypes: []string{"reload"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/drafts/"+d.ID+"/events"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			ids, types := readEvents(t, resp, len(tt.wantTypes))
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(types, tt.wantTypes) {
+				t.Errorf("got events %v %v, want %v %v", ids, types, tt.wantIDs, tt.wantTypes)
+			}
+		})
+	}
+
+	// Every stream unsubscribes once its page goes away
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		d.events.mu.Lock()
+		subscribers := len(d.events.subscribers)
+		d.events.mu.Unlock()
+		if subscribers == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%v subscribers left after every stream closed", subscribers)
+		}
+	}
+}
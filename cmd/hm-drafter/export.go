@@ -0,0 +1,330 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Export is a finished draft's rosters and pick history, ready to write out as CSV, JSON or Markdown
+type Export struct {
+	DraftID    string        `json:"draftID"`
+	Tournament string        `json:"tournament"`
+	Date       string        `json:"date"`
+	Fields     []ExportField `json:"fields"`
+	Teams      []ExportTeam  `json:"teams"`
+	Picks      []ExportPick  `json:"picks"`
+}
+
+// ExportField is a form field included in the export
+type ExportField struct {
+	Slug  string `json:"slug"`
+	Label string `json:"label"`
+}
+
+type ExportTeam struct {
+	Name    string         `json:"name"`
+	Players []ExportPlayer `json:"players"`
+}
+
+type ExportPlayer struct {
+	Name     string            `json:"name"`
+	AltName  string            `json:"altName,omitempty"`
+	Pronouns string            `json:"pronouns,omitempty"`
+	Captain  bool              `json:"captain"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Pick     *ExportPick       `json:"pick,omitempty"` // How they ended up on the team, nil for captains and balanced teams
+}
+
+type ExportPick struct {
+	Number      int    `json:"number"`
+	Round       int    `json:"round"`
+	PickInRound int    `json:"pickInRound"`
+	Captain     string `json:"captain"`
+	Player      string `json:"player"`
+	Team        string `json:"team"`
+	Price       int    `json:"price,omitempty"`
+	Auto        bool   `json:"auto,omitempty"`
+}
+
+// exportFormats maps each export format to its content type and file extension
+var exportFormats = map[string]struct {
+	ContentType string
+	Extension   string
+}{
+	"csv":  {"text/csv; charset=utf-8", "csv"},
+	"json": {"application/json; charset=utf-8", "json"},
+	"md":   {"text/markdown; charset=utf-8", "md"},
+}
+
+// Export gathers the draft's rosters and picks. fields lists the form field slugs to include, or every field but the alt name if it's empty.
+func (d *Draft) Export(fields []string) Export {
+	export := Export{
+		DraftID:    d.ID,
+		Tournament: d.TournamentName(),
+	}
+	if len(d.SelectedTournament) > 2 {
+		export.Date = d.SelectedTournament[2]
+	}
+
+	for _, field := range d.FormFields {
+		slug, label := field.Slug, field.Label()
+		if (len(fields) == 0 && slug != "altname") || containsString(fields, slug) {
+			export.Fields = append(export.Fields, ExportField{Slug: slug, Label: label})
+		}
+	}
+
+	teamNames := make(map[int]string)
+	for _, team := range d.Teams {
+		teamNames[team.ID] = team.Name
+	}
+
+	picksByPlayer := make(map[float64]*ExportPick)
+	for _, pick := range d.Picks {
+		export.Picks = append(export.Picks, ExportPick{
+			Number:      pick.Number,
+			Round:       pick.Round,
+			PickInRound: d.pickInRound(pick),
+			Captain:     pick.CaptainName,
+			Player:      pick.PlayerName,
+			Team:        teamNames[pick.TeamID],
+			Price:       pick.Price,
+			Auto:        pick.Auto,
+		})
+	}
+	for i, pick := range d.Picks {
+		picksByPlayer[pick.PlayerID] = &export.Picks[i]
+	}
+
+	captains := make(map[float64]bool)
+	for _, captain := range d.Captains {
+		captains[captain.ID] = true
+	}
+
+	for _, team := range d.Teams {
+		exportTeam := ExportTeam{Name: team.Name}
+		for _, player := range team.Players {
+			exportPlayer := ExportPlayer{
+				Name:     player.Name,
+				AltName:  player.AltName(),
+				Pronouns: player.Pronouns,
+				Captain:  captains[player.ID],
+				Fields:   make(map[string]string),
+				Pick:     picksByPlayer[player.ID],
+			}
+			for _, field := range export.Fields {
+				exportPlayer.Fields[field.Slug] = player.Field(field.Slug).String()
+			}
+			exportTeam.Players = append(exportTeam.Players, exportPlayer)
+		}
+		export.Teams = append(export.Teams, exportTeam)
+	}
+
+	return export
+}
+
+// pickInRound works out a pick's place in its round. Turn-based picks follow the draft's sequence, auction picks just count off in the order they were sold.
+func (d *Draft) pickInRound(pick Pick) int {
+	if sequence := d.sequence(); d.Mode != auctionMode && pick.Number <= len(sequence) {
+		return sequence[pick.Number-1].PickInRound
+	}
+	if len(d.DraftOrder) == 0 {
+		return 0
+	}
+	return (pick.Number-1)%len(d.DraftOrder) + 1
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteExport writes the export in the given format: "csv", "json" or "md"
+func WriteExport(w io.Writer, export Export, format string) error {
+	switch format {
+	case "csv":
+		return writeExportCSV(w, export)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(export)
+	case "md":
+		return writeExportMarkdown(w, export)
+	}
+	return fmt.Errorf("unknown export format %q, use csv, json or md", format)
+}
+
+// writeExportCSV writes one row per rostered player, with the pick that put them on their team
+func writeExportCSV(w io.Writer, export Export) error {
+	out := csv.NewWriter(w)
+
+	header := []string{"Team", "Player", "Alt Name", "Pronouns", "Captain", "Pick #", "Round", "Pick in Round", "Picked By"}
+	for _, field := range export.Fields {
+		header = append(header, field.Label)
+	}
+	out.Write(header)
+
+	for _, team := range export.Teams {
+		for _, player := range team.Players {
+			row := []string{team.Name, player.Name, player.AltName, player.Pronouns, strconv.FormatBool(player.Captain), "", "", "", ""}
+			if pick := player.Pick; pick != nil {
+				row[5] = strconv.Itoa(pick.Number)
+				row[6] = strconv.Itoa(pick.Round)
+				row[7] = strconv.Itoa(pick.PickInRound)
+				row[8] = pick.Captain
+			}
+			for _, field := range export.Fields {
+				row = append(row, player.Fields[field.Slug])
+			}
+			out.Write(row)
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// writeExportMarkdown writes a recap that pastes straight into Discord
+func writeExportMarkdown(w io.Writer, export Export) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %v Draft Recap\n", export.Tournament)
+	if export.Date != "" {
+		fmt.Fprintf(&b, "*%v*\n", export.Date)
+	}
+
+	for _, team := range export.Teams {
+		fmt.Fprintf(&b, "\n## %v\n", team.Name)
+		for _, player := range team.Players {
+			line := "- " + player.Name
+			if player.AltName != "" {
+				line += " (" + player.AltName + ")"
+			}
+			if player.Captain {
+				line += " :crown:"
+			}
+			if player.Pronouns != "" {
+				line += " - " + player.Pronouns
+			}
+			if pick := player.Pick; pick != nil {
+				line += fmt.Sprintf(" - Round %d, Pick %d", pick.Round, pick.PickInRound)
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+
+	if len(export.Picks) > 0 {
+		b.WriteString("\n## Pick History\n")
+		for _, pick := range export.Picks {
+			fmt.Fprintf(&b, "%d. R%d P%d - %v picked %v", pick.Number, pick.Round, pick.PickInRound, pick.Captain, pick.Player)
+			if pick.Price > 0 {
+				fmt.Fprintf(&b, " for %d", pick.Price)
+			}
+			if pick.Auto {
+				b.WriteString(" (auto-pick)")
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// exportFilename builds a download name like "pdx-mixer-november-draft.csv"
+func exportFilename(export Export, format string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, export.Tournament)
+
+	for strings.Contains(name, "--") {
+		name = strings.ReplaceAll(name, "--", "-")
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = export.DraftID
+	}
+
+	return name + "-draft." + exportFormats[format].Extension
+}
+
+// splitFields reads a comma separated list of form field slugs
+func splitFields(list string) (fields []string) {
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// runExportCommand handles "hm-drafter export", which writes a saved draft's export without starting the server
+func runExportCommand(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	storeSpec := fs.String("store", envOr("DRAFT_STORE", "file:data/drafts"), "where drafts are saved: file:<dir> or kv:<file>")
+	format := fs.String("format", "md", "export format: csv, json or md")
+	fields := fs.String("fields", "", "comma separated form field slugs to include, defaults to every field but the alt name")
+	output := fs.String("o", "", "file to write to, defaults to stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: hm-drafter export [flags] <draft ID>")
+		fmt.Fprintln(fs.Output(), "Without a draft ID, lists the drafts in the store.")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	store, err := OpenDraftStore(*storeSpec)
+	if err != nil {
+		return err
+	}
+	saved, err := store.LoadAll()
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		for _, d := range saved {
+			fmt.Printf("%v\t%v\t%v\n", d.ID, d.Stage(), d.TournamentName())
+		}
+		return nil
+	}
+
+	var d *Draft
+	for _, candidate := range saved {
+		if candidate.ID == fs.Arg(0) {
+			d = candidate
+		}
+	}
+	if d == nil {
+		return fmt.Errorf("draft %q isn't in %v", fs.Arg(0), *storeSpec)
+	}
+	if _, ok := exportFormats[*format]; !ok {
+		return fmt.Errorf("unknown export format %q, use csv, json or md", *format)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return WriteExport(w, d.Export(splitFields(*fields)), *format)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// exportDraft is a finished draft of 6 players, 2 captains and 4 picks, with skill and alt name fields
+func exportDraft(t *testing.T) *Draft {
+	t.Helper()
+
+	d := localDraft(t, 6, 2)
+	d.SelectedTournament = []string{"1", "PDX Mixer: November!", "2026-11-07"}
+	d.FormFields = []FormField{
+		{Slug: "skill", Description: "Skill level", Type: "number"},
+		{Slug: "altname", Description: "Alt name", Type: "text"},
+	}
+	for _, list := range [][]Player{d.Players, d.DraftPlayers} {
+		for i := range list {
+			if list[i].ID == 4 {
+				list[i].FormFields["altname"] = StringValue("Four")
+				list[i].Pronouns = "they/them"
+			}
+		}
+	}
+	for _, id := range []float64{3, 4, 5, 6} {
+		if _, err := d.PickPlayer(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Picks[3].Auto = true
+	return d
+}
+
+func TestExportFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []string
+		want   []string
+	}{
+		{name: "every field but the alt name by default", want: []string{"skill"}},
+		{name: "just the alt name", fields: []string{"altname"}, want: []string{"altname"}},
+		{name: "in the form's order", fields: []string{"altname", "skill"}, want: []string{"skill", "altname"}},
+		{name: "fields the form doesn't have", fields: []string{"email"}},
+	}
+
+	d := exportDraft(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := d.Export(tt.fields)
+			var got []string
+			for _, field := range export.Fields {
+				got = append(got, field.Slug)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got fields %v, want %v", got, tt.want)
+			}
+
+			// Every player has an answer for every exported field and nothing else
+			for _, team := range export.Teams {
+				for _, player := range team.Players {
+					if len(player.Fields) != len(tt.want) {
+						t.Errorf("%v has fields %v, want %v", player.Name, player.Fields, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	export := exportDraft(t).Export(nil)
+
+	if export.DraftID != "test" || export.Tournament != "PDX Mixer: November!" || export.Date != "2026-11-07" {
+		t.Errorf("got draft %q, tournament %q and date %q", export.DraftID, export.Tournament, export.Date)
+	}
+
+	// Snake order: the first captain picks 3, the second 4 and 5, then the first 6 when the clock ran out
+	wantPicks := []ExportPick{
+		{Number: 1, Round: 1, PickInRound: 1, Captain: "Player 1", Player: "Player 3", Team: "Player 1's Team"},
+		{Number: 2, Round: 1, PickInRound: 2, Captain: "Player 2", Player: "Player 4", Team: "Player 2's Team"},
+		{Number: 3, Round: 2, PickInRound: 1, Captain: "Player 2", Player: "Player 5", Team: "Player 2's Team"},
+		{Number: 4, Round: 2, PickInRound: 2, Captain: "Player 1", Player: "Player 6", Team: "Player 1's Team", Auto: true},
+	}
+	if !reflect.DeepEqual(export.Picks, wantPicks) {
+		t.Errorf("got picks %+v, want %+v", export.Picks, wantPicks)
+	}
+
+	tests := []struct {
+		team     int
+		player   int
+		name     string
+		captain  bool
+		pick     int // The pick number that put them on the team, 0 for captains
+		altName  string
+		pronouns string
+	}{
+		{team: 0, player: 0, name: "Player 1", captain: true},
+		{team: 0, player: 1, name: "Player 3", pick: 1},
+		{team: 0, player: 2, name: "Player 6", pick: 4},
+		{team: 1, player: 0, name: "Player 2", captain: true},
+		{team: 1, player: 1, name: "Player 4", pick: 2, altName: "Four", pronouns: "they/them"},
+		{team: 1, player: 2, name: "Player 5", pick: 3},
+	}
+	if len(export.Teams) != 2 {
+		t.Fatalf("got %v teams, want 2", len(export.Teams))
+	}
+	for _, tt := range tests {
+		team := export.Teams[tt.team]
+		if len(team.Players) != 3 {
+			t.Fatalf("%v has %v players, want 3", team.Name, len(team.Players))
+		}
+		player := team.Players[tt.player]
+		if player.Name != tt.name || player.Captain != tt.captain || player.AltName != tt.altName || player.Pronouns != tt.pronouns {
+			t.Errorf("%v player %v is %+v, want %v", team.Name, tt.player, player, tt.name)
+		}
+		switch {
+		case tt.pick == 0 && player.Pick != nil:
+			t.Errorf("captain %v has pick %+v", player.Name, player.Pick)
+		case tt.pick != 0 && (player.Pick == nil || *player.Pick != export.Picks[tt.pick-1]):
+			t.Errorf("%v has pick %+v, want pick %v", player.Name, player.Pick, tt.pick)
+		}
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	export := exportDraft(t).Export([]string{"skill"})
+
+	t.Run("csv", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := WriteExport(&b, export, "csv"); err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(&b).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{
+			{"Team", "Player", "Alt Name", "Pronouns", "Captain", "Pick #", "Round", "Pick in Round", "Picked By", "Skill level"},
+			{"Player 1's Team", "Player 1", "", "", "true", "", "", "", "", "1"},
+			{"Player 1's Team", "Player 3", "", "", "false", "1", "1", "1", "Player 1", "3"},
+			{"Player 1's Team", "Player 6", "", "", "false", "4", "2", "2", "Player 1", "1"},
+			{"Player 2's Team", "Player 2", "", "", "true", "", "", "", "", "2"},
+			{"Player 2's Team", "Player 4", "Four", "they/them", "false", "2", "1", "2", "Player 2", "4"},
+			{"Player 2's Team", "Player 5", "", "", "false", "3", "2", "1", "Player 2", "5"},
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("got rows\n%q\nwant\n%q", rows, want)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := WriteExport(&b, export, "json"); err != nil {
+			t.Fatal(err)
+		}
+		var got Export
+		if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, export) {
+			t.Errorf("the JSON reads back as %+v, want %+v", got, export)
+		}
+	})
+
+	t.Run("md", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := WriteExport(&b, export, "md"); err != nil {
+			t.Fatal(err)
+		}
+		want := `# PDX Mixer: November! Draft Recap
+*2026-11-07*
+
+## Player 1's Team
+- Player 1 :crown:
+- Player 3 - Round 1, Pick 1
+- Player 6 - Round 2, Pick 2
+
+## Player 2's Team
+- Player 2 :crown:
+- Player 4 (Four) - they/them - Round 1, Pick 2
+- Player 5 - Round 2, Pick 1
+
+## Pick History
+1. R1 P1 - Player 1 picked Player 3
+2. R1 P2 - Player 2 picked Player 4
+3. R2 P1 - Player 2 picked Player 5
+4. R2 P2 - Player 1 picked Player 6 (auto-pick)
+`
+		if b.String() != want {
+			t.Errorf("got\n%v\nwant\n%v", b.String(), want)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		var b bytes.Buffer
+		if err := WriteExport(&b, export, "xlsx"); err == nil || b.Len() != 0 {
+			t.Errorf("got error %v and %q written, want an error and nothing written", err, b.String())
+		}
+	})
+}
+
+func TestExportFilename(t *testing.T) {
+	tests := []struct {
+		tournament string
+		format     string
+		want       string
+	}{
+		{tournament: "PDX Mixer: November!", format: "csv", want: "pdx-mixer-november-draft.csv"},
+		{tournament: "  Queens & Drones 2026 ", format: "md", want: "queens-drones-2026-draft.md"},
+		{tournament: "Ünïcode", format: "json", want: "n-code-draft.json"},
+		{tournament: "", format: "json", want: "test-draft.json"},
+		{tournament: "!!!", format: "csv", want: "test-draft.csv"},
+	}
+	for _, tt := range tests {
+		export := Export{DraftID: "test", Tournament: tt.tournament}
+		if got := exportFilename(export, tt.format); got != tt.want {
+			t.Errorf("exportFilename(%q, %q) = %q, want %q", tt.tournament, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{list: "", want: nil},
+		{list: "skill", want: []string{"skill"}},
+		{list: " skill, roles ,,altname,", want: []string{"skill", "roles", "altname"}},
+		{list: " , ", want: nil},
+	}
+	for _, tt := range tests {
+		if got := splitFields(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitFields(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
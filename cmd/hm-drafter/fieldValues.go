@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kinds of form field value
+const (
+	stringValue = "string"
+	numberValue = "number"
+	boolValue   = "bool"
+	listValue   = "list"
+)
+
+// FieldValue is one answer to a form field, kept as the type it was given in so players can be filtered by a single role or sorted by skill. It's saved as plain JSON: a string, number, true/false or list of strings.
+type FieldValue struct {
+	Kind string
+	Str  string
+	Num  float64
+	Bool bool
+	List []string
+}
+
+func StringValue(s string) FieldValue     { return FieldValue{Kind: stringValue, Str: s} }
+func NumberValue(n float64) FieldValue    { return FieldValue{Kind: numberValue, Num: n} }
+func BoolValue(b bool) FieldValue         { return FieldValue{Kind: boolValue, Bool: b} }
+func ListValue(items []string) FieldValue { return FieldValue{Kind: listValue, List: items} }
+
+// ParseFieldValue turns a raw answer from HiveMind, a spreadsheet or a saved draft into a typed value. Strings are read as the field's type, so a CSV "3" for skill becomes a number and "Queen, Speed Warrior" for roles becomes a list.
+func ParseFieldValue(field FormField, raw interface{}) FieldValue {
+	switch v := raw.(type) {
+	case nil:
+		return FieldValue{}
+	case bool:
+		return BoolValue(v)
+	case float64:
+		return NumberValue(v)
+	case int:
+		return NumberValue(float64(v))
+	case []string:
+		return ListValue(v)
+	case []interface{}:
+		items := []string{}
+		for _, item := range v {
+			items = append(items, safeString(item))
+		}
+		return ListValue(items)
+	case string:
+		return parseFieldString(field, v)
+	}
+	return StringValue(fmt.Sprintf("%v", raw))
+}
+
+func parseFieldString(field FormField, s string) FieldValue {
+	s = strings.TrimSpace(s)
+	switch field.Type {
+	case numberField:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return NumberValue(n)
+		}
+	case checkboxField:
+		switch strings.ToLower(s) {
+		case "true", "yes", "y", "1", "on", "x":
+			return BoolValue(true)
+		case "false", "no", "n", "0", "off", "":
+			return BoolValue(false)
+		}
+	case multiselectField:
+		return ListValue(splitList(s))
+	}
+	return StringValue(s)
+}
+
+// splitList splits a comma separated answer, dropping blanks
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// IsZero reports whether the field wasn't answered
+func (v FieldValue) IsZero() bool {
+	return v.Kind == ""
+}
+
+// String renders the value for pages and exports: lists comma separated and booleans as Yes/No
+func (v FieldValue) String() string {
+	switch v.Kind {
+	case numberValue:
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case boolValue:
+		if v.Bool {
+			return "Yes"
+		}
+		return "No"
+	case listValue:
+		return strings.Join(v.List, ", ")
+	}
+	return v.Str
+}
+
+// Number returns the value as a number. Strings count if they start with one, so "3 - comfortable" is 3, the way skill answers are often written.
+func (v FieldValue) Number() (float64, bool) {
+	switch v.Kind {
+	case numberValue:
+		return v.Num, true
+	case boolValue:
+		if v.Bool {
+			return 1, true
+		}
+		return 0, true
+	case stringValue:
+		return leadingNumber(v.Str)
+	}
+	return 0, false
+}
+
+// Items returns the value as a list. Strings are split on commas, since drafts saved before values had types kept lists that way.
+func (v FieldValue) Items() []string {
+	switch v.Kind {
+	case listValue:
+		return v.List
+	case stringValue:
+		return splitList(v.Str)
+	case "":
+		return nil
+	}
+	return []string{v.String()}
+}
+
+// Truthy reports whether a yes/no answer is yes
+func (v FieldValue) Truthy() bool {
+	switch v.Kind {
+	case boolValue:
+		return v.Bool
+	case numberValue:
+		return v.Num != 0
+	}
+	return parseFieldString(FormField{Type: checkboxField}, v.String()).Bool
+}
+
+// Has reports whether the answer is, or for a list includes, choice. Case is ignored.
+func (v FieldValue) Has(choice string) bool {
+	if v.Kind == boolValue {
+		// Only yes/no words can match a checkbox, anything else (e.g. Queen) isn't an answer it could have
+		want := parseFieldString(FormField{Type: checkboxField}, choice)
+		return want.Kind == boolValue && v.Bool == want.Bool
+	}
+	for _, item := range v.Items() {
+		if strings.EqualFold(item, choice) {
+			return true
+		}
+	}
+	return strings.EqualFold(v.String(), choice)
+}
+
+// Compare orders two values: numbers numerically, anything else alphabetically. Unanswered values sort last.
+func (v FieldValue) Compare(other FieldValue) int {
+	switch {
+	case v.IsZero() && other.IsZero():
+		return 0
+	case v.IsZero():
+		return 1
+	case other.IsZero():
+		return -1
+	}
+
+	a, aOK := v.Number()
+	b, bOK := other.Number()
+	if aOK && bOK {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(v.String()), strings.ToLower(other.String()))
+}
+
+func (v FieldValue) MarshalJSON() ([]byte, error) {
+	switch v.Kind {
+	case numberValue:
+		return json.Marshal(v.Num)
+	case boolValue:
+		return json.Marshal(v.Bool)
+	case listValue:
+		return json.Marshal(v.List)
+	case "":
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.Str)
+}
+
+// UnmarshalJSON reads a saved value. Drafts saved before values had types only have strings, which stay strings and are read as numbers or lists when asked.
+func (v *FieldValue) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*v = FieldValue{}
+		return nil
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if s, ok := raw.(string); ok {
+		*v = StringValue(s)
+		return nil
+	}
+	*v = ParseFieldValue(FormField{}, raw)
+	return nil
+}
+
+// retypeFieldValues reads the plain string answers of drafts saved before values had types as their field's type
+func (d *Draft) retypeFieldValues() {
+	retype := func(players []Player) {
+		for _, player := range players {
+			for _, field := range d.FormFields {
+				if value, ok := player.FormFields[field.Slug]; ok && value.Kind == stringValue {
+					player.FormFields[field.Slug] = parseFieldString(field, value.Str)
+				}
+			}
+		}
+	}
+
+	retype(d.Players)
+	retype(d.DraftPlayers)
+	for _, team := range d.Teams {
+		retype(team.Players)
+	}
+}
+
+// leadingNumber reads the first number in s
+func leadingNumber(s string) (float64, bool) {
+	start := strings.IndexFunc(s, unicode.IsDigit)
+	if start < 0 {
+		return 0, false
+	}
+	end := start
+	for end < len(s) && (unicode.IsDigit(rune(s[end])) || s[end] == '.') {
+		end++
+	}
+	n, err := strconv.ParseFloat(s[start:end], 64)
+	return n, err == nil
+}
+
+// Field returns a player's answer to a form field, or the zero value if they didn't answer
+func (p Player) Field(slug string) FieldValue {
+	return p.FormFields[slug]
+}
+
+// AltName is the player's alternate or in-game name, if the tournament asked for one
+func (p Player) AltName() string {
+	return p.Field("altname").String()
+}
+
+// FilterPlayers keeps the players whose answer to the field is, or includes, value
+func FilterPlayers(players []Player, slug, value string) (matches []Player) {
+	for _, player := range players {
+		if player.Field(slug).Has(value) {
+			matches = append(matches, player)
+		}
+	}
+	return matches
+}
+
+// SortPlayers orders players by a field, numerically where the answers are numbers. Ties keep their order.
+func SortPlayers(players []Player, slug string, descending bool) []Player {
+	sorted := append([]Player(nil), players...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Field(slug), sorted[j].Field(slug)
+		// Unanswered stays last either way
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if descending {
+			return a.Compare(b) > 0
+		}
+		return a.Compare(b) < 0
+	})
+	return sorted
+}
+
+// CountPlayers counts the players whose answer to the field is, or includes, value. Roster constraints use it to check things like every team having a Queen.
+func CountPlayers(players []Player, slug, value string) int {
+	return len(FilterPlayers(players, slug, value))
+}
+
+// SumField adds up a numeric field across players, skipping anyone without a number
+func SumField(players []Player, slug string) (total float64) {
+	for _, player := range players {
+		if n, ok := player.Field(slug).Number(); ok {
+			total += n
+		}
+	}
+	return total
+}
+
+// bestAvailableSort ranks players by skill, going by HiveMind ratings when the draft uses them
+const bestAvailableSort = "best"
+
+// PlayerView filters and sorts the list of available players, e.g. ?filter=roles:Queen&sort=-skill for Queens, best first
+type PlayerView struct {
+	Filter  string   // "slug:value"
+	Sort    string   // A field slug, with a leading "-" for descending, or "best"
+	Ratings *Ratings // What "best" goes by, nil for self-reported skill
+}
+
+// Apply returns the players the view shows, leaving players as they are
+func (v PlayerView) Apply(players []Player) []Player {
+	if slug, value, found := strings.Cut(v.Filter, ":"); found {
+		players = FilterPlayers(players, slug, value)
+	}
+	if v.Sort == bestAvailableSort {
+		players = append([]Player(nil), players...)
+		sort.SliceStable(players, func(i, j int) bool { return v.Ratings.Skill(players[i]) > v.Ratings.Skill(players[j]) })
+	} else if v.Sort != "" {
+		slug := strings.TrimPrefix(v.Sort, "-")
+		players = SortPlayers(players, slug, strings.HasPrefix(v.Sort, "-"))
+	}
+	return players
+}
+
+// ViewOption is one choice in the filter and sort menus
+type ViewOption struct {
+	Value string
+	Label string
+}
+
+// playerViewOptions lists what the players list can be filtered and sorted by: every choice of the fields that have them, and every field
+func playerViewOptions(fields []FormField) (filters, sorts []ViewOption) {
+	for _, field := range fields {
+		switch field.Type {
+		case selectField, multiselectField:
+			for _, choice := range field.Choices {
+				filters = append(filters, ViewOption{Value: field.Slug + ":" + choice, Label: field.Label() + ": " + choice})
+			}
+		case checkboxField:
+			filters = append(filters, ViewOption{Value: field.Slug + ":yes", Label: field.Label() + ": Yes"})
+		}
+
+		sorts = append(sorts, ViewOption{Value: field.Slug, Label: field.Label() + " (low to high)"})
+		if field.Type == numberField {
+			sorts = append(sorts, ViewOption{Value: "-" + field.Slug, Label: field.Label() + " (high to low)"})
+		}
+	}
+	return filters, sorts
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldValue(t *testing.T) {
+	skill := FormField{Slug: "skill", Type: numberField}
+	roles := FormField{Slug: "roles", Type: multiselectField, Choices: []string{"Queen", "Speed Warrior"}}
+	flexible := FormField{Slug: "flexible", Type: checkboxField}
+	notes := FormField{Slug: "notes", Type: textField}
+
+	tests := []struct {
+		name  string
+		field FormField
+		raw   interface{}
+		want  FieldValue
+	}{
+		{name: "unanswered", field: skill, raw: nil, want: FieldValue{}},
+		{name: "JSON number", field: skill, raw: 3.0, want: NumberValue(3)},
+		{name: "int", field: skill, raw: 4, want: NumberValue(4)},
+		{name: "number as text", field: skill, raw: " 2 ", want: NumberValue(2)},
+		{name: "unreadable number stays text", field: skill, raw: "3 - comfortable", want: StringValue("3 - comfortable")},
+		{name: "comma separated list", field: roles, raw: "Queen, Speed Warrior,", want: ListValue([]string{"Queen", "Speed Warrior"})},
+		{name: "JSON list", field: roles, raw: []interface{}{"Queen"}, want: ListValue([]string{"Queen"})},
+		{name: "yes", field: flexible, raw: "Yes", want: BoolValue(true)},
+		{name: "x", field: flexible, raw: "x", want: BoolValue(true)},
+		{name: "blank checkbox", field: flexible, raw: "", want: BoolValue(false)},
+		{name: "unreadable checkbox stays text", field: flexible, raw: "maybe", want: StringValue("maybe")},
+		{name: "JSON bool", field: notes, raw: true, want: BoolValue(true)},
+		{name: "text", field: notes, raw: " hi ", want: StringValue("hi")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseFieldValue(tt.field, tt.raw); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldValueHas(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  FieldValue
+		choice string
+		want   bool
+	}{
+		{name: "list item", value: ListValue([]string{"Queen", "Speed Warrior"}), choice: "speed warrior", want: true},
+		{name: "list without it", value: ListValue([]string{"Queen"}), choice: "Objective Runner", want: false},
+		{name: "comma separated text", value: StringValue("Queen, Speed Warrior"), choice: "Queen", want: true},
+		{name: "number", value: NumberValue(3), choice: "3", want: true},
+		{name: "yes checkbox", value: BoolValue(true), choice: "Yes", want: true},
+		{name: "yes checkbox against no", value: BoolValue(false), choice: "yes", want: false},
+		{name: "no checkbox", value: BoolValue(false), choice: "No", want: true},
+		{name: "no checkbox against a role", value: BoolValue(false), choice: "Queen", want: false},
+		{name: "yes checkbox against a role", value: BoolValue(true), choice: "Queen", want: false},
+		{name: "unanswered", value: FieldValue{}, choice: "Queen", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.Has(tt.choice); got != tt.want {
+				t.Errorf("%v.Has(%q) = %v, want %v", tt.value, tt.choice, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldValueCompare(t *testing.T) {
+	tests := []struct {
+		a, b FieldValue
+		want int
+	}{
+		{a: NumberValue(2), b: NumberValue(10), want: -1},
+		{a: StringValue("10"), b: NumberValue(2), want: 1},
+		{a: StringValue("apple"), b: StringValue("Banana"), want: -1},
+		{a: NumberValue(3), b: StringValue("3 - comfortable"), want: 0},
+		{a: FieldValue{}, b: NumberValue(1), want: 1},
+		{a: NumberValue(1), b: FieldValue{}, want: -1},
+		{a: FieldValue{}, b: FieldValue{}, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.a.Compare(tt.b); got != tt.want {
+			t.Errorf("%#v.Compare(%#v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFieldValueJSON(t *testing.T) {
+	tests := []struct {
+		value FieldValue
+		json  string
+	}{
+		{value: NumberValue(2.5), json: `2.5`},
+		{value: BoolValue(true), json: `true`},
+		{value: ListValue([]string{"Queen"}), json: `["Queen"]`},
+		{value: StringValue("7"), json: `"7"`}, // Text stays text, it's retyped by the draft's fields
+		{value: FieldValue{}, json: `null`},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.json {
+			t.Errorf("%#v marshals to %s, want %s", tt.value, data, tt.json)
+		}
+
+		var back FieldValue
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(back, tt.value) {
+			t.Errorf("%s reads back as %#v, want %#v", data, back, tt.value)
+		}
+	}
+}
@@ -1,41 +1,175 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+
+	"github.com/imandradesign/hm-drafter/hivemind"
+)
+
+// Form field types. HiveMind doesn't always say what type a field is, anything unknown is treated as text.
+const (
+	textField        = "text"
+	numberField      = "number"
+	checkboxField    = "checkbox"
+	selectField      = "select"
+	multiselectField = "multiselect"
+	emailField       = "email"
 )
 
+// FormField is one of a tournament's registration questions
+type FormField struct {
+	Name        string   // HiveMind's randomly assigned field name, which player answers are keyed by
+	Slug        string   // Stable name for the field, e.g. "skill" or "roles"
+	Description string   // The question as asked on the form
+	Type        string   // text, number, checkbox, select, multiselect or email
+	Choices     []string `json:",omitempty"` // Options for select and multiselect fields
+	Required    bool     `json:",omitempty"`
+	Min         int      `json:",omitempty"` // Range for number fields, ignored when both are 0
+	Max         int      `json:",omitempty"`
+}
+
+// fieldDefaults fill in the type and choices for the fields the draft has always known about, for tournaments whose fields don't say
+var fieldDefaults = map[string]FormField{
+	"skill":    {Type: numberField, Required: true, Min: 1, Max: 5},
+	"roles":    {Type: multiselectField, Required: true, Choices: []string{"Queen", "Speed Warrior", "Vanilla Warrior", "Objective Runner"}},
+	"flexible": {Type: checkboxField},
+	"sub":      {Type: checkboxField},
+	"coach":    {Type: checkboxField},
+	"captain":  {Type: selectField, Choices: []string{"Yes", "No"}},
+	"email":    {Type: emailField},
+}
+
+// NewFormField builds a field's metadata, filling in the type from fieldDefaults or text
+func NewFormField(name, slug, description, fieldType string, choices []string, required bool) FormField {
+	field := FormField{Name: name, Slug: slug, Description: description, Type: fieldType, Choices: choices, Required: required}
+
+	if defaults, ok := fieldDefaults[slug]; ok && field.Type == "" {
+		field.Type = defaults.Type
+		field.Required = field.Required || defaults.Required
+		field.Min, field.Max = defaults.Min, defaults.Max
+		if len(field.Choices) == 0 {
+			field.Choices = defaults.Choices
+		}
+	}
+	switch field.Type {
+	case textField, numberField, checkboxField, selectField, multiselectField, emailField:
+	case "multi-select", "multiple_choice":
+		field.Type = multiselectField
+	case "boolean", "bool":
+		field.Type = checkboxField
+	case "choice", "dropdown":
+		field.Type = selectField
+	default:
+		field.Type = textField
+	}
+
+	// A choice field without choices can only be answered as text
+	if (field.Type == selectField || field.Type == multiselectField) && len(field.Choices) == 0 {
+		field.Type = textField
+	}
+	return field
+}
+
+// Label is what the field is called on player cards and exports
+func (f FormField) Label() string {
+	if f.Description != "" {
+		return f.Description
+	}
+	return f.Slug
+}
+
+// UnmarshalJSON also reads the [name, slug, description] lists drafts were saved with before fields had types
+func (f *FormField) UnmarshalJSON(data []byte) error {
+	var legacy []string
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		for len(legacy) < 3 {
+			legacy = append(legacy, "")
+		}
+		*f = NewFormField(legacy[0], legacy[1], legacy[2], "", nil, false)
+		return nil
+	}
+
+	type plain FormField
+	return json.Unmarshal(data, (*plain)(f))
+}
+
+// formFieldFromHiveMind converts HiveMind's field metadata
+func formFieldFromHiveMind(field hivemind.FormField) FormField {
+	return NewFormField(field.FieldName, field.FieldSlug, field.FieldDescription, field.FieldType, field.Choices, field.IsRequired)
+}
+
+// findFormField looks up one of the fields by slug
+func findFormField(fields []FormField, slug string) (FormField, bool) {
+	for _, field := range fields {
+		if field.Slug == slug {
+			return field, true
+		}
+	}
+	return FormField{}, false
+}
+
 // GetFormFields takes the API string that lists all tourney form fields, checks the `results` list entries and returns the form fields with their randomly assigned name
-func GetFormFields(tournamentId string) (fields [][]string) {
+func GetFormFields(ctx context.Context, tournamentId string) (fields []FormField, err error) {
 	log.Println("Fetching form field data...")
 
-	api := fmt.Sprintf(apiTemplate, "player-info-field", fmt.Sprintf("&tournament_id=%v", tournamentId), "")
-		
-	client, req := createRequest("GET", api, nil)
-
-	resp, err := client.Do(req)
+	id, err := parseID(tournamentId)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("invalid tournament ID %q: %w", tournamentId, err)
 	}
 
-	// Defer the closing of the body
-	defer resp.Body.Close()
-
-	// Decode the response into APIResponse struct
-	var formApiResponse FormApiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&formApiResponse); err != nil {
-		log.Fatal(err)
+	results, err := hm.FormFields(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching form fields: %w", err)
 	}
 
-	for _, field := range formApiResponse.Results {
-		fields = append(fields, []string{
-			field.FieldName,
-			field.FieldSlug,
-			field.FieldDescription,
-		})
+	for _, field := range results {
+		fields = append(fields, formFieldFromHiveMind(field))
 	}
 
 	log.Println("API data fetched.")
+	return fields, nil
+}
+
+// boardFields returns the fields shown on the draft's player cards, in form order. The alt name is left out since it's shown next to the player's name.
+func (d *Draft) boardFields() (fields []FormField) {
+	for _, field := range d.FormFields {
+		if field.Slug == "altname" {
+			continue
+		}
+		// Email addresses stay off the cards unless the organizer chooses to show them
+		if (d.BoardFields == nil && field.Type != emailField) || containsString(d.BoardFields, field.Slug) {
+			fields = append(fields, field)
+		}
+	}
 	return fields
-}
\ No newline at end of file
+}
+
+// shownFields is the set of slugs on the player cards
+func (d *Draft) shownFields() map[string]bool {
+	shown := make(map[string]bool)
+	for _, field := range d.boardFields() {
+		shown[field.Slug] = true
+	}
+	return shown
+}
+
+// SetBoardFields picks which fields the player cards show
+func (d *Draft) SetBoardFields(slugs []string) error {
+	shown := []string{}
+	for _, slug := range slugs {
+		if _, ok := findFormField(d.FormFields, slug); !ok {
+			return fmt.Errorf("%q isn't one of this tournament's form fields", slug)
+		}
+		shown = append(shown, slug)
+	}
+
+	d.BoardFields = shown
+	log.Printf("Draft %v: player cards show %v", d.ID, shown)
+
+	// Every open page needs its cards redrawn
+	d.publish("reload", nil)
+	return nil
+}
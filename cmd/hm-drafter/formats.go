@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Slot is one pick in a draft: which round it's in, its place in that round and overall, and which captain (by position in the draft order) makes it
+type Slot struct {
+	Round        int
+	PickInRound  int
+	Overall      int
+	CaptainIndex int
+}
+
+// DraftFormat decides the order captains pick in
+type DraftFormat interface {
+	// Name is the value the teams page posts and the draft saves
+	Name() string
+	// Label is the name shown to organizers
+	Label() string
+	// Sequence returns every pick in the draft for the given number of captains and picks
+	Sequence(captains, picks int) []Slot
+}
+
+// draftFormats lists the formats organizers can choose from on the teams page
+var draftFormats = []DraftFormat{
+	SnakeFormat{},
+	LinearFormat{},
+	ThirdRoundReversalFormat{},
+	CustomFormat{},
+}
+
+// DraftFormatByName returns the format with the given name. Custom formats also need the organizer's pick sequence, e.g. "1,2,3,3,1,2".
+func DraftFormatByName(name string, custom string) (DraftFormat, error) {
+	switch name {
+	case "", "snake":
+		return SnakeFormat{}, nil
+	case "linear":
+		return LinearFormat{}, nil
+	case "third-round-reversal":
+		return ThirdRoundReversalFormat{}, nil
+	case "custom":
+		return ParseCustomFormat(custom)
+	}
+	return nil, fmt.Errorf("unknown draft format %q", name)
+}
+
+// roundSequence builds a sequence round by round, letting reversed decide which rounds run from the last captain to the first
+func roundSequence(captains, picks int, reversed func(round int) bool) (slots []Slot) {
+	if captains == 0 {
+		return nil
+	}
+
+	for overall := 1; overall <= picks; overall++ {
+		round := (overall-1)/captains + 1
+		pickInRound := (overall-1)%captains + 1
+
+		index := pickInRound - 1
+		if reversed(round) {
+			index = captains - pickInRound
+		}
+
+		slots = append(slots, Slot{Round: round, PickInRound: pickInRound, Overall: overall, CaptainIndex: index})
+	}
+
+	return slots
+}
+
+// LinearFormat has every round go in the same order
+type LinearFormat struct{}
+
+func (LinearFormat) Name() string  { return "linear" }
+func (LinearFormat) Label() string { return "Linear" }
+
+func (LinearFormat) Sequence(captains, picks int) []Slot {
+	return roundSequence(captains, picks, func(int) bool { return false })
+}
+
+// SnakeFormat reverses the order every round, so the last captain in one round picks first in the next
+type SnakeFormat struct{}
+
+func (SnakeFormat) Name() string  { return "snake" }
+func (SnakeFormat) Label() string { return "Snake" }
+
+func (SnakeFormat) Sequence(captains, picks int) []Slot {
+	return roundSequence(captains, picks, func(round int) bool { return round%2 == 0 })
+}
+
+// ThirdRoundReversalFormat is a snake draft where round 3 repeats round 2's order, which softens the advantage of picking first
+type ThirdRoundReversalFormat struct{}
+
+func (ThirdRoundReversalFormat) Name() string  { return "third-round-reversal" }
+func (ThirdRoundReversalFormat) Label() string { return "Third-Round Reversal" }
+
+func (ThirdRoundReversalFormat) Sequence(captains, picks int) []Slot {
+	return roundSequence(captains, picks, func(round int) bool {
+		if round <= 3 {
+			return round >= 2
+		}
+		return round%2 == 1
+	})
+}
+
+// CustomFormat follows a hand-written list of draft positions (1 = first captain in the draft order), repeating it until every player is picked
+type CustomFormat struct {
+	Positions []int
+}
+
+// ParseCustomFormat reads a comma or space separated list of draft positions
+func ParseCustomFormat(sequence string) (CustomFormat, error) {
+	var positions []int
+	for _, field := range strings.FieldsFunc(sequence, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' }) {
+		position, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || position < 1 {
+			return CustomFormat{}, fmt.Errorf("invalid draft position %q in custom sequence", field)
+		}
+		positions = append(positions, position)
+	}
+	if len(positions) == 0 {
+		return CustomFormat{}, fmt.Errorf("the custom pick sequence is empty")
+	}
+	return CustomFormat{Positions: positions}, nil
+}
+
+// Validate checks every position in the sequence belongs to one of the captains
+func (f CustomFormat) Validate(captains int) error {
+	for _, position := range f.Positions {
+		if position > captains {
+			return fmt.Errorf("the custom sequence uses draft position %d but there are only %d captains", position, captains)
+		}
+	}
+	return nil
+}
+
+func (CustomFormat) Name() string  { return "custom" }
+func (CustomFormat) Label() string { return "Custom Sequence" }
+
+// String returns the sequence in the same form organizers type it
+func (f CustomFormat) String() string {
+	parts := make([]string, len(f.Positions))
+	for i, position := range f.Positions {
+		parts[i] = strconv.Itoa(position)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f CustomFormat) Sequence(captains, picks int) (slots []Slot) {
+	if captains == 0 || len(f.Positions) == 0 {
+		return nil
+	}
+
+	for overall := 1; overall <= picks; overall++ {
+		position := f.Positions[(overall-1)%len(f.Positions)]
+		slots = append(slots, Slot{
+			Round:        (overall-1)/captains + 1,
+			PickInRound:  (overall-1)%captains + 1,
+			Overall:      overall,
+			CaptainIndex: (position - 1) % captains,
+		})
+	}
+
+	return slots
+}
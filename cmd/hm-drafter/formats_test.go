@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// captainOrder lists which captain (1 = first in the draft order) makes each pick
+func captainOrder(slots []Slot) []int {
+	order := make([]int, len(slots))
+	for i, slot := range slots {
+		order[i] = slot.CaptainIndex + 1
+	}
+	return order
+}
+
+func TestFormatSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		custom   string
+		captains int
+		picks    int
+		want     []int
+	}{
+		{name: "linear", format: "linear", captains: 3, picks: 7, want: []int{1, 2, 3, 1, 2, 3, 1}},
+		{name: "snake", format: "snake", captains: 3, picks: 9, want: []int{1, 2, 3, 3, 2, 1, 1, 2, 3}},
+		{name: "default is snake", format: "", captains: 2, picks: 5, want: []int{1, 2, 2, 1, 1}},
+		{name: "third round reversal", format: "third-round-reversal", captains: 3, picks: 15, want: []int{1, 2, 3, 3, 2, 1, 3, 2, 1, 1, 2, 3, 3, 2, 1}},
+		{name: "custom repeats", format: "custom", custom: "1,2,2,1", captains: 2, picks: 6, want: []int{1, 2, 2, 1, 1, 2}},
+		{name: "custom with spaces", format: "custom", custom: "3 1 2", captains: 3, picks: 4, want: []int{3, 1, 2, 3}},
+		{name: "no captains", format: "snake", captains: 0, picks: 4, want: []int{}},
+		{name: "no picks", format: "linear", captains: 3, picks: 0, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := DraftFormatByName(tt.format, tt.custom)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slots := format.Sequence(tt.captains, tt.picks)
+			if got := captainOrder(slots); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+
+			// Rounds and places in the round count off the same way in every format
+			for i, slot := range slots {
+				if slot.Overall != i+1 || slot.Round != i/tt.captains+1 || slot.PickInRound != i%tt.captains+1 {
+					t.Errorf("pick %d is %+v", i+1, slot)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCustomFormat(t *testing.T) {
+	tests := []struct {
+		sequence string
+		captains int
+		want     string
+		wantErr  bool
+	}{
+		{sequence: "1,2,3", captains: 3, want: "1,2,3"},
+		{sequence: " 2, 1 ,1\n2 ", captains: 2, want: "2,1,1,2"},
+		{sequence: "", wantErr: true},
+		{sequence: "1,0", wantErr: true},
+		{sequence: "1,two", wantErr: true},
+		{sequence: "1,4", captains: 3, wantErr: true}, // Position past the last captain
+	}
+
+	for _, tt := range tests {
+		format, err := ParseCustomFormat(tt.sequence)
+		if err == nil && tt.captains > 0 {
+			err = format.Validate(tt.captains)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: got error %v, want error %v", tt.sequence, err, tt.wantErr)
+			continue
+		}
+		if err == nil && format.String() != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.sequence, format.String(), tt.want)
+		}
+	}
+}
+
+func TestDraftFormatByNameUnknown(t *testing.T) {
+	if _, err := DraftFormatByName("random", ""); err == nil {
+		t.Error("an unknown format didn't return an error")
+	}
+}
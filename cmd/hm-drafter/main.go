@@ -1,142 +1,668 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
-	"time"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/imandradesign/hm-drafter/hivemind"
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
 )
 
-const (
-	apiTemplate      = "https://kqhivemind.com/api/tournament/%s/?format=json%s%s"
-	apiFormFieldsSlug = "player-info-field"
-	apiTeamsSlug      = "team"
-	apiPlayersSlug    = "player"
-	scene            = "kqpdx"
-)
+// maxImportSize caps uploaded player files, a mixer's worth of registrations is a few KB
+const maxImportSize = 5 << 20
 
 var (
-	selectedTournament   []string
-	formFields           [][]string
-	tournamentID         string
-	players              []Player
-	playerCount          int
-	captains             []Captain
-	captainNamesFromForm []string
-	captainCount         int
-	playersOnDeletedTeam []string
-	draftPlayers         []Player
-	draftOrder           []Captain
-	remainingPlayerCount int
-	currentCaptainIndex  int
-	draftDirection       int
-	teams                []TeamInfo
-	unassignedCaptains   []Captain
+	hm     *hivemind.Client
+	drafts = NewDraftRegistry(nil)
 )
 
-// Helper function to create an HTTP request with the API key
-// Parameters:
-// - method: HTTP method (e.g., "GET", "POST").
-// - url: The URL for the request.
-// - body: The request body, can be nil for methods like GET.
-// Returns:
-// - client: An HTTP client with a timeout.
-// - req: The created HTTP request with the Authorization header set.
-func createRequest(method, url string, body io.Reader) (client *http.Client, req *http.Request) {
-	client = &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		log.Fatal(err)
-		return nil, nil
-	}
-
+// newHivemindClient builds the HiveMind API client from the environment. A non-empty baseURL overrides the API root.
+func newHivemindClient(baseURL string) *hivemind.Client {
 	// Retrieve the API key from the environment
 	apiKey := os.Getenv("API_KEY")
 	if apiKey == "" {
-		log.Fatal("API key not set in environment")
+		log.Println("API key not set in environment, HiveMind writes will be rejected")
+	}
+
+	var opts []hivemind.Option
+	if baseURL != "" {
+		opts = append(opts, hivemind.WithBaseURL(baseURL))
+	}
+
+	return hivemind.NewClient(apiKey, opts...)
+}
+
+// envOr returns the environment variable, or fallback if it's unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
+}
 
-	// Attach the Authorization header
-	req.Header.Add("Authorization", "Token "+apiKey)
+// showError logs an error and renders the error page instead of taking the server down
+func showError(c *gin.Context, status int, err error) {
+	log.Printf("Error handling %v %v: %v", c.Request.Method, c.Request.URL.Path, err)
 
-	return client, req
+	// Errors inside a draft keep the draft's scene branding
+	branding := BrandingFor(c.Query("scene"))
+	if d, ok := c.Get("draft"); ok {
+		branding = d.(*Draft).Branding()
+	}
+
+	c.HTML(status, "error.html", gin.H{
+		"branding": branding,
+		"status":   status,
+		"error":    err.Error(),
+		"back":     c.Request.Referer(),
+	})
 }
 
 func main() {
+	// Subcommands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := runExportCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		return
+	}
+
+	hivemindURL := flag.String("hivemind-url", os.Getenv("HIVEMIND_URL"), "HiveMind API root, e.g. http://localhost:8001/api for a local fake-hivemind")
+	fakeFixtures := flag.String("fake-hivemind", "", "run against an in-process fake HiveMind seeded from this fixtures file")
+	storeSpec := flag.String("store", envOr("DRAFT_STORE", "file:data/drafts"), "where drafts are saved: file:<dir>, kv:<file> or memory")
+	importFile := flag.String("import", "", "start a draft from a CSV or JSON file of players instead of a HiveMind tournament")
+	importMapping := flag.String("import-mapping", "", "CSV column mapping for -import, e.g. \"Full Name=name;Skill Level=skill\"")
+	flag.StringVar(&defaultScene, "scene", envOr("SCENE", defaultScene), "HiveMind scene the lobby shows tournaments for, e.g. kqpdx")
+	brandingFile := flag.String("branding", os.Getenv("BRANDING"), "JSON file of per-scene titles, colors and logos")
+	closesSpec := flag.String("registration-closes", os.Getenv("REGISTRATION_CLOSES"), "when self-registration closes, e.g. \"104=2026-11-14 18:00;105=2026-12-12 18:00\"")
+	flag.Parse()
+
+	closes, err := ParseRegistrationCloses(*closesSpec)
+	if err != nil {
+		log.Fatalf("Failed to read -registration-closes: %v", err)
+	}
+	registrationCloses = closes
+
+	defaultScene = normalizeScene(defaultScene)
+	if *brandingFile != "" {
+		if err := LoadBrandings(*brandingFile); err != nil {
+			log.Fatalf("Failed to load branding: %v", err)
+		}
+	}
+
 	port := os.Getenv("PORT")
 
 	if port == "" {
 		port = "8000" // Default
 	}
 
+	if *fakeFixtures != "" {
+		fx, err := hivemindtest.LoadFixtures(*fakeFixtures)
+		if err != nil {
+			log.Fatalf("Failed to load fake HiveMind fixtures: %v", err)
+		}
+		fake := hivemindtest.Start(fx)
+		defer fake.Close()
+
+		*hivemindURL = fake.URL + "/api"
+		log.Printf("Using fake HiveMind at %v", *hivemindURL)
+	}
+
+	hm = newHivemindClient(*hivemindURL)
+
+	// Fetch the scene list up front so draft pages have their scene's colors without waiting on the lobby
+	go func() {
+		if _, err := scenes.List(context.Background()); err != nil {
+			log.Printf("Fetching scenes: %v", err)
+		}
+	}()
+
+	store, err := OpenDraftStore(*storeSpec)
+	if err != nil {
+		log.Fatalf("Failed to open draft store: %v", err)
+	}
+	if os.Getenv("DYNO") != "" && *storeSpec != "memory" {
+		log.Printf("Saving drafts to %v on a Heroku dyno, they'll be lost when the dyno restarts", *storeSpec)
+	}
+	drafts = NewDraftRegistry(store)
+
+	restored, err := drafts.Restore()
+	if err != nil {
+		log.Fatalf("Failed to restore drafts: %v", err)
+	}
+	log.Printf("Restored %v unfinished draft(s) from %v", restored, *storeSpec)
+
+	if *importFile != "" {
+		if err := importDraft(*importFile, *importMapping); err != nil {
+			log.Fatalf("Failed to import %v: %v", *importFile, err)
+		}
+	}
+
+	router := setupRouter()
+
+	err = router.Run(":" + port)
+	if err != nil {
+		log.Fatalf("Failed to start the server: %v", err)
+	}
+}
+
+// loadDraft looks up the draft named in the URL and holds its lock for the rest of the request, so two people clicking at once can't interleave picks
+func loadDraft(c *gin.Context) {
+	d := drafts.Get(c.Param("id"))
+	if d == nil {
+		showError(c, http.StatusNotFound, fmt.Errorf("draft %q not found, it may have been closed", c.Param("id")))
+		c.Abort()
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c.Set("draft", d)
+	c.Next()
+
+	// Every POST changes the draft, so save it before the lock is released
+	if c.Request.Method != http.MethodGet {
+		if err := drafts.Save(d); err != nil {
+			log.Printf("Failed to save draft %v: %v", d.ID, err)
+		}
+	}
+}
+
+// importDraft starts a draft from a local players file
+func importDraft(path, mapping string) error {
+	columns, err := ParseColumnMapping(strings.ReplaceAll(mapping, ";", "\n"))
+	if err != nil {
+		return err
+	}
+
+	var source PlayerSource = JSONFileSource{Path: path}
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		source = fileSource(path, data, columns)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	d, err := drafts.Start(context.Background(), source, defaultScene, []string{"", name, ""}, "")
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Imported %v players from %v into draft %v, organize it from %v", len(d.Players), path, d.ID, d.OrganizerURL())
+	return nil
+}
+
+// draftURL builds a link to a page within a draft
+func draftURL(d *Draft, page string) string {
+	if page == "" {
+		return "/drafts/" + d.ID
+	}
+	return "/drafts/" + d.ID + "/" + page
+}
+
+// setupRouter registers every route on a new gin engine. It expects the templates and static directories relative to the working directory.
+func setupRouter() *gin.Engine {
 	router := gin.Default()
 
 	// Load HTML templates
-	router.LoadHTMLFiles("templates/index.html", "templates/drafting.html", "templates/teams.html", "templates/done.html")
+	router.LoadHTMLFiles("templates/lobby.html", "templates/index.html", "templates/drafting.html", "templates/teams.html", "templates/done.html", "templates/error.html", "templates/auction.html", "templates/balance.html", "templates/captain.html", "templates/spectate.html", "templates/overlay.html", "templates/branding.html", "templates/tournaments.html", "templates/tournament.html", "templates/register.html", "templates/registered.html", "templates/board-fields.html", "templates/roster.html", "templates/roster-warning.html", "templates/pairs.html", "templates/mix.html", "templates/ratings.html", "templates/captain-links.html")
 
 	router.Static("/static", "./static")
 
-	// Home page route
+	// Live updates for every page of a draft
+	router.GET("/drafts/:id/events", streamEvents)
+
+	// Lobby route, lists the active drafts and starts new ones
 	router.GET("/", func(c *gin.Context) {
-		// Fetch tournament data
-		tournaments := GetPDXTournies()
-
-		c.HTML(http.StatusOK, "index.html", gin.H{
-			"tournaments":        tournaments,
-			"selectedTournament": selectedTournament,
-			"playerCount":        playerCount,
-			"players":            players,
+		ctx := c.Request.Context()
+		scene := normalizeScene(c.Query("scene"))
+
+		// The scene picker still works off the default scene if HiveMind won't list them
+		sceneList, err := scenes.List(ctx)
+		if err != nil {
+			log.Printf("Fetching scenes for the lobby: %v", err)
+		}
+
+		// Fetch tournament data. Imported drafts don't need HiveMind, so the lobby still works without it.
+		tournaments, err := GetSceneTournies(ctx, scene)
+		if err != nil {
+			log.Printf("Fetching tournaments for the lobby: %v", err)
+		}
+
+		c.HTML(http.StatusOK, "lobby.html", gin.H{
+			"branding":         BrandingFor(scene),
+			"scene":            scene,
+			"scenes":           sceneList,
+			"tournaments":      tournaments,
+			"tournamentsError": err,
+			"drafts":           drafts.List(),
 		})
 	})
 
-	// Handle the form submission for tournament selection
-	router.POST("/confirm", func(c *gin.Context) {
-		// Get the selected tournament ID from the form
-		tournamentIndexStr := c.PostForm("tournament")
-		tournamentIndex, err := strconv.Atoi(tournamentIndexStr)
+	// Browse a scene's tournaments by name and date
+	router.GET("/tournaments", func(c *gin.Context) {
+		scene := normalizeScene(c.Query("scene"))
+		page, _ := strconv.Atoi(c.Query("page"))
+
+		filter := TournamentFilter{
+			Scene: scene,
+			Query: c.Query("q"),
+			When:  c.Query("when"),
+			From:  c.Query("from"),
+			To:    c.Query("to"),
+			Page:  page,
+		}
+		results, err := SearchTournaments(c.Request.Context(), filter)
 		if err != nil {
-			c.String(http.StatusBadRequest, "Invalid tournament selection")
+			showError(c, http.StatusBadGateway, err)
 			return
 		}
 
-		// Fetch tournaments and retrieve the selected one
-		tournaments := GetPDXTournies()
-		if tournamentIndex >= 0 && tournamentIndex < len(tournaments) {
-			selectedTournament = tournaments[tournamentIndex]
-		} else {
-			c.String(http.StatusBadRequest, "Tournament not found")
+		// Page links keep the rest of the search
+		query := c.Request.URL.Query()
+		pageURL := func(page int) string {
+			query.Set("page", strconv.Itoa(page))
+			return "/tournaments?" + query.Encode()
+		}
+		var prevURL, nextURL string
+		if results.Page > 1 {
+			prevURL = pageURL(results.Page - 1)
+		}
+		if results.Page < results.Pages {
+			nextURL = pageURL(results.Page + 1)
+		}
+
+		c.HTML(http.StatusOK, "tournaments.html", gin.H{
+			"branding": BrandingFor(scene),
+			"scene":    scene,
+			"filter":   filter,
+			"results":  results,
+			"prevURL":  prevURL,
+			"nextURL":  nextURL,
+		})
+	})
+
+	// A tournament's draft setup page, which organizers can bookmark
+	router.GET("/tournaments/:tournamentID", func(c *gin.Context) {
+		tournament, err := GetTournament(c.Request.Context(), c.Param("tournamentID"))
+		if err != nil {
+			showError(c, tournamentErrorStatus(err), err)
+			return
+		}
+
+		// Drafts already running for this tournament
+		var running []*Draft
+		for _, d := range drafts.List() {
+			if d.TournamentID == strconv.Itoa(tournament.ID) {
+				running = append(running, d)
+			}
+		}
+
+		c.HTML(http.StatusOK, "tournament.html", gin.H{
+			"branding":   BrandingFor(tournament.SceneName),
+			"tournament": tournament,
+			"drafts":     running,
+		})
+	})
+
+	// Player self-registration for a HiveMind tournament
+	router.GET("/tournaments/:tournamentID/register", func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		tournament, err := GetTournament(ctx, c.Param("tournamentID"))
+		if err != nil {
+			showError(c, tournamentErrorStatus(err), err)
+			return
+		}
+		formFields, err := GetFormFields(ctx, strconv.Itoa(tournament.ID))
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
 			return
 		}
 
-		tournamentID = selectedTournament[0]
+		c.HTML(http.StatusOK, "register.html", registrationPageData(tournament, formFields, nil))
+	})
+
+	router.POST("/add-player", func(c *gin.Context) {
+		ctx := c.Request.Context()
 
-		// Fetch form fields for selected tournament
-		formFields = GetFormFields(tournamentID)
+		tournament, err := GetTournament(ctx, c.PostForm("tournamentID"))
+		if err != nil {
+			showError(c, tournamentErrorStatus(err), err)
+			return
+		}
+		tournamentID := strconv.Itoa(tournament.ID)
 
-		// Fetch player data
-		players = GetPlayersData(tournamentID)
-		playerCount = len(players)
-		log.Printf("# of players: %v", playerCount)
+		formFields, err := GetFormFields(ctx, tournamentID)
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if !registrationOpen(tournamentID) {
+			c.HTML(http.StatusForbidden, "register.html", registrationPageData(tournament, formFields, nil))
+			return
+		}
+
+		if err := c.Request.ParseForm(); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		reg, problems := ParseRegistration(tournamentID, c.Request.PostForm, formFields)
+
+		if len(problems) == 0 {
+			_, err = RegisterPlayer(ctx, reg, formFields)
+			switch {
+			case errors.Is(err, errDuplicateRegistration):
+				problems = append(problems, fmt.Sprintf("Someone with that name or email is already registered for %v. Ask the organizer if you need to change your registration.", tournament.Name))
+			case err != nil:
+				showError(c, http.StatusBadGateway, err)
+				return
+			}
+		}
+
+		// Send the form back with what they typed so they only have to fix the problems
+		if len(problems) > 0 {
+			c.HTML(http.StatusBadRequest, "register.html", registrationPageData(tournament, formFields, gin.H{
+				"problems": problems,
+				"values":   c.Request.PostForm,
+			}))
+			return
+		}
+
+		c.HTML(http.StatusOK, "registered.html", registrationPageData(tournament, formFields, gin.H{
+			"registration": reg,
+			"answers":      reg.AnswerList(formFields),
+		}))
+	})
+
+	// Handle the form submission for tournament selection, which starts a new draft session
+	router.POST("/drafts", func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		// Look the tournament up by ID, so the draft is for the tournament that was picked even if the list has changed since
+		tournament, err := GetTournament(ctx, c.PostForm("tournamentID"))
+		if err != nil {
+			showError(c, tournamentErrorStatus(err), err)
+			return
+		}
+		tournamentID := strconv.Itoa(tournament.ID)
+
+		scene := tournament.SceneName
+		if scene == "" {
+			scene = c.PostForm("scene")
+		}
+
+		// Fetch the form fields and players for the selected tournament
+		d, err := drafts.Start(ctx, HiveMindSource{TournamentID: tournamentID}, scene, selectedTournament(*tournament), tournamentID)
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		// Whoever starts a draft runs it
+		signInOrganizer(c, d)
+		c.Redirect(http.StatusFound, draftURL(d, ""))
+	})
+
+	// Start a draft from an uploaded spreadsheet or JSON file instead of HiveMind. Its teams are kept in the draft.
+	router.POST("/drafts/import", func(c *gin.Context) {
+		upload, err := c.FormFile("players")
+		if err != nil {
+			c.String(http.StatusBadRequest, "Choose a CSV or JSON file of players to import.")
+			return
+		}
+		file, err := upload.Open()
+		if err != nil {
+			showError(c, http.StatusBadRequest, err)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxImportSize))
+		if err != nil {
+			showError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		mapping, err := ParseColumnMapping(c.PostForm("mapping"))
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		name := strings.TrimSpace(c.PostForm("name"))
+		if name == "" {
+			name = strings.TrimSuffix(upload.Filename, filepath.Ext(upload.Filename))
+		}
+
+		source := fileSource(upload.Filename, data, mapping)
+		d, err := drafts.Start(c.Request.Context(), source, c.PostForm("scene"), []string{"", name, c.PostForm("date")}, "")
+		if err != nil {
+			showError(c, http.StatusBadRequest, err)
+			return
+		}
+
+		signInOrganizer(c, d)
+		c.Redirect(http.StatusFound, draftURL(d, ""))
+	})
+
+	// Captains type their short code into the lobby to get to their pick page
+	router.GET("/c", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/c/"+url.PathEscape(strings.TrimSpace(c.Query("code"))))
+	})
+
+	router.GET("/c/:code", func(c *gin.Context) {
+		d := drafts.DraftForCaptainCode(c.Param("code"))
+		if d == nil {
+			showError(c, http.StatusNotFound, fmt.Errorf("captain code %q not found, check it with the organizer", c.Param("code")))
+			return
+		}
+
+		d.mu.Lock()
+		link, ok := d.CaptainLinkByCode(c.Param("code"))
+		d.mu.Unlock()
+		if !ok {
+			showError(c, http.StatusNotFound, fmt.Errorf("captain code %q not found, check it with the organizer", c.Param("code")))
+			return
+		}
+
+		c.Redirect(http.StatusFound, link.URL(d.ID))
+	})
+
+	draft := router.Group("/drafts/:id", loadDraft)
+
+	// Everything that changes how the draft is set up or run is only for the organizer
+	organizer := draft.Group("", requireOrganizer)
+
+	// The organizer link signs this browser in as the draft's organizer
+	draft.GET("/organize/:key", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if !d.organizerKeyMatches(c.Param("key")) {
+			showError(c, http.StatusForbidden, fmt.Errorf("this organizer link isn't valid for draft %v", d.ID))
+			return
+		}
+
+		signInOrganizer(c, d)
+		c.Redirect(http.StatusFound, draftURL(d, ""))
+	})
+
+	// The captains' private links, only for the organizer so they never end up on the projector
+	organizer.GET("/captain-links", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		c.HTML(http.StatusOK, "captain-links.html", d.pageData(gin.H{
+			"captainLinks": d.CaptainLinks,
+			"organizerURL": d.OrganizerURL(),
+		}))
+	})
+
+	// Captain selection page for a draft
+	draft.GET("", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		c.HTML(http.StatusOK, "index.html", d.pageData(nil))
+	})
+
+	// Close a draft session and drop it from the lobby
+	organizer.POST("/close", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if err := drafts.Remove(d.ID); err != nil {
+			log.Printf("Failed to delete saved draft %v: %v", d.ID, err)
+		}
+		log.Printf("Closed draft %v", d.ID)
 
-		// Stay on homepage when confirming tournament selection
 		c.Redirect(http.StatusFound, "/")
 	})
 
+	// Choose which form fields show on the player cards
+	organizer.POST("/board-fields", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if err := d.SetBoardFields(c.PostFormArray("fields")); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		back := c.Request.Referer()
+		if back == "" {
+			back = draftURL(d, "")
+		}
+		c.Redirect(http.StatusFound, back)
+	})
+
+	// Set the roster rules picks are checked against
+	organizer.POST("/roster-rules", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if err := d.SetRosterRules(c.PostForm("rules"), c.PostForm("enforcement")); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		back := c.Request.Referer()
+		if back == "" {
+			back = draftURL(d, "teams")
+		}
+		c.Redirect(http.StatusFound, back)
+	})
+
+	// Record that two players want to play together, or be kept apart
+	organizer.POST("/pairs/add", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		playerA, errA := parseID(c.PostForm("playerA"))
+		playerB, errB := parseID(c.PostForm("playerB"))
+		if errA != nil || errB != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+
+		if err := d.AddPairRequest(c.PostForm("kind"), float64(playerA), float64(playerB), strings.TrimSpace(c.PostForm("note"))); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		back := c.Request.Referer()
+		if back == "" {
+			back = draftURL(d, "")
+		}
+		c.Redirect(http.StatusFound, back)
+	})
+
+	organizer.POST("/pairs/remove", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		playerA, errA := parseID(c.PostForm("playerA"))
+		playerB, errB := parseID(c.PostForm("playerB"))
+		if errA != nil || errB != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+
+		if err := d.RemovePairRequest(float64(playerA), float64(playerB)); err != nil {
+			showError(c, http.StatusInternalServerError, err)
+			return
+		}
+
+		back := c.Request.Referer()
+		if back == "" {
+			back = draftURL(d, "")
+		}
+		c.Redirect(http.StatusFound, back)
+	})
+
+	// Turn mix it up mode on or off
+	organizer.POST("/mix-it-up", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if err := d.SetMixItUp(c.Request.Context(), c.PostForm("mix") == "on"); err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		back := c.Request.Referer()
+		if back == "" {
+			back = draftURL(d, "")
+		}
+		c.Redirect(http.StatusFound, back)
+	})
+
+	// Load or refresh the scene's ratings from HiveMind, and choose whether the draft goes by them
+	organizer.POST("/ratings", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		// Loading from HiveMind can take a while, so it runs in the background and the draft's pages reload when it's done
+		if c.PostForm("refresh") != "" {
+			if drafts.RefreshRatingsInBackground(d.sceneName()) {
+				d.publish("reload", nil)
+			}
+		}
+
+		if use := c.PostForm("use"); use != "" {
+			if err := d.SetUseRatings(use == "on"); err != nil {
+				showError(c, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		back := c.Request.Referer()
+		if back == "" {
+			back = draftURL(d, "")
+		}
+		c.Redirect(http.StatusFound, back)
+	})
+
 	// Handle the form submission for captain selection
-	router.POST("/confirm-captains", func(c *gin.Context) {
-		// Get the selected captains from the form
-		captainNamesFromForm = c.PostFormArray("selectedPlayers")
-		captains := ExtractCaptains(captainNamesFromForm, players)
-		captainCount = len(captains)
+	organizer.POST("/confirm-captains", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
 
+		// Starting over would drop the picks here but leave the players on their HiveMind teams
+		if len(d.Picks) > 0 {
+			c.String(http.StatusBadRequest, "Players have already been drafted. Undo every pick before changing the captains.")
+			return
+		}
+
+		// Get the selected captains from the form
+		captainNamesFromForm := c.PostFormArray("selectedPlayers")
+		captains := ExtractCaptains(captainNamesFromForm, d.Players)
 
 		// If no captains were selected, return an error message
 		if len(captains) == 0 {
@@ -145,143 +671,678 @@ func main() {
 		}
 
 		// Save initial draft info
-		draftPlayers = RemoveCaptainsFromPlayers(players, captains)
-		remainingPlayerCount = len(draftPlayers)
+		d.Captains = captains
+		d.DraftPlayers = RemoveCaptainsFromPlayers(d.Players, captains)
 
-		// Set initial values for the draft state
-		draftOrder = GenerateDraftOrder(captains)
-		currentCaptainIndex = 0 // Start with the first captain
-		draftDirection = 1      // Start with ascending order
+		// Set initial values for the draft state, starting with a snake draft until the organizer picks a format
+		d.DraftOrder = GenerateDraftOrder(captains)
+		if err := d.SetFormat(SnakeFormat{}.Name(), ""); err != nil {
+			showError(c, http.StatusInternalServerError, err)
+			return
+		}
 
-		unassignedCaptains = draftOrder
+		d.UnassignedCaptains = d.DraftOrder
 
-		c.Redirect(http.StatusFound, "/teams")
+		// Every captain gets a private link to make their own picks from their phone
+		drafts.issueCaptainLinks(d)
+
+		c.Redirect(http.StatusFound, draftURL(d, "teams"))
 	})
 
 	// Teams page route
-	router.GET("/teams", func(c *gin.Context) {
-		teams = GetTeams(tournamentID, players)
-
-		log.Printf("Unassigned Captain Data:\n%v", unassignedCaptains)
-
-		c.HTML(http.StatusOK, "teams.html", gin.H{
-			"selectedTournament":  selectedTournament,
-			"remainingPlayerCount": remainingPlayerCount,
-			"playerCount":         playerCount,
-			"captainCount":        captainCount,
-			"unassignedCaptains":  unassignedCaptains,
-			"draftOrder":          draftOrder,
-			"teams":               teams,
-		})
+	draft.GET("/teams", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		var err error
+		d.Teams, err = d.teamStore().Teams(c.Request.Context(), d.Players)
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		log.Printf("Unassigned Captain Data:\n%v", d.UnassignedCaptains)
+
+		c.HTML(http.StatusOK, "teams.html", d.pageData(gin.H{
+			"draftFormats":   draftFormats,
+			"selectedFormat": d.Format,
+			"customSequence": d.CustomSequence,
+			"organizer":      d.IsOrganizer(c),
+		}))
 	})
 
 	// Handle the form submission for adding new teams
-	router.POST("/add-team", func(c *gin.Context) {
+	organizer.POST("/add-team", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
 		teamName := c.PostForm("teamAddition")
 
-		AddNewTeam(teamName, tournamentID)
+		if _, err := d.teamStore().AddTeam(c.Request.Context(), teamName); err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+		d.refreshTeams(c.Request.Context())
 
-		c.Redirect(http.StatusFound, "/teams")
+		c.Redirect(http.StatusFound, draftURL(d, "teams"))
 	})
 
 	// Handle the form submission for deleting teams
-	router.POST("/remove-team", func(c *gin.Context) {
+	organizer.POST("/remove-team", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
 		teamID := c.PostForm("teamDeletion")
 		log.Printf("Team ID for removal: %v", teamID)
 
-		teamName := GetTeamNameByID(teams, teamID)
+		teamName := GetTeamNameByID(d.Teams, teamID)
 		log.Printf("Team name for removal: %v", teamName)
 
-		playersOnDeletedTeam = DeleteTeam(teamID, teamName, tournamentID)
+		playersOnDeletedTeam, err := d.teamStore().DeleteTeam(c.Request.Context(), teamID, teamName)
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		// Anyone on the deleted team is teamless now, and any captain that led it needs a new team
+		deletedTeam, _ := parseID(teamID)
+		for _, playerID := range playersOnDeletedTeam {
+			if id, err := parseID(playerID); err == nil {
+				d.setPlayerTeam(float64(id), 0)
+			}
+		}
+		for _, captain := range d.Captains {
+			if captain.TeamID == deletedTeam {
+				d.setCaptainTeam(captain.ID, 0)
+				d.UnassignedCaptains = UpdateUnassignedCaptains(formatID(captain.ID), d.UnassignedCaptains, d.Players, true)
+			}
+		}
+		d.refreshTeams(c.Request.Context())
 
-		c.Redirect(http.StatusFound, "/teams")
+		c.Redirect(http.StatusFound, draftURL(d, "teams"))
 	})
 
-	router.POST("/assign-captain",func(c *gin.Context) {
+	organizer.POST("/assign-captain", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
 		cap := c.PostForm("captainID")
 		team := c.PostForm("teamID")
 
 		log.Printf("Captain ID: %v\nTeam ID: %v", cap, team)
 
-		AssignPlayerToTeam(cap, team, tournamentID)
-		unassignedCaptains = UpdateUnassignedCaptains(cap, unassignedCaptains, false)
+		captainID, err := parseID(cap)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid captain")
+			return
+		}
+		teamID, err := parseID(team)
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid team")
+			return
+		}
+
+		if err := d.teamStore().AssignPlayer(c.Request.Context(), cap, team); err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+		d.setCaptainTeam(float64(captainID), teamID)
+		d.UnassignedCaptains = UpdateUnassignedCaptains(cap, d.UnassignedCaptains, d.Players, false)
+		d.refreshTeams(c.Request.Context())
 
-		c.Redirect(http.StatusFound, "/teams")
+		c.Redirect(http.StatusFound, draftURL(d, "teams"))
 	})
 
 	// Redirect to Drafting page after confirming teams
-	router.POST("/confirm-teams", func(c *gin.Context) {
+	organizer.POST("/confirm-teams", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
 		// If no teams exist, return an error message
-		if len(teams) == 0 {
+		if len(d.Teams) == 0 {
 			c.String(http.StatusBadRequest, "No teams created. Please create at least one team.")
 			return
 		}
-		
+
+		// Picks go onto the captain's team, so every captain needs one first
+		if len(d.UnassignedCaptains) > 0 {
+			c.String(http.StatusBadRequest, "Every captain needs a team before the draft can start. Still unassigned: %v", d.UnassignedCaptains[0].Name)
+			return
+		}
+
+		// Lay out the picks in the chosen format, or set up budgets for an auction
+		if len(d.Picks) == 0 {
+			if c.PostForm("mode") == auctionMode {
+				budget, _ := strconv.Atoi(c.PostForm("budget"))
+				minBid, _ := strconv.Atoi(c.PostForm("minBid"))
+				bidSeconds, _ := strconv.Atoi(c.PostForm("bidSeconds"))
+				if err := d.StartAuction(budget, minBid, bidSeconds); err != nil {
+					c.String(http.StatusBadRequest, err.Error())
+					return
+				}
+			} else {
+				d.Mode = turnsMode
+				d.Auction = nil
+				if err := d.SetFormat(c.PostForm("format"), c.PostForm("customSequence")); err != nil {
+					c.String(http.StatusBadRequest, err.Error())
+					return
+				}
+
+				clockSeconds, _ := strconv.Atoi(c.PostForm("clockSeconds"))
+				clockPerRound, _ := strconv.Atoi(c.PostForm("clockPerRound"))
+				if err := d.SetClock(clockSeconds, clockPerRound); err != nil {
+					c.String(http.StatusBadRequest, err.Error())
+					return
+				}
+				drafts.startPickClock(d)
+			}
+		}
+
 		// Update teams with the latest data
-		teams = GetTeams(tournamentID, players)
+		var err error
+		d.Teams, err = d.teamStore().Teams(c.Request.Context(), d.Players)
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+		d.publishTurn()
 
-		c.Redirect(http.StatusFound, "/drafting")
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
 	})
 
 	// Drafting page route
-	router.GET("/drafting", func(c *gin.Context) {
-		teams = GetTeams(tournamentID, players)
-		currCaptain := draftOrder[currentCaptainIndex].Name
-
-		c.HTML(http.StatusOK, "drafting.html", gin.H{
-			"selectedTournament": selectedTournament,
-			"captainCount": captainCount,
-			"remainingPlayerCount": remainingPlayerCount,
-			"draftOrder": draftOrder,
-			"draftPlayers": draftPlayers,
-			"currentCaptain": currCaptain,
-			"teams": teams,
-		})
+	draft.GET("/drafting", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if len(d.DraftOrder) == 0 {
+			c.Redirect(http.StatusFound, draftURL(d, ""))
+			return
+		}
+		if d.Mode == auctionMode {
+			c.Redirect(http.StatusFound, draftURL(d, "auction"))
+			return
+		}
+
+		var err error
+		d.Teams, err = d.teamStore().Teams(c.Request.Context(), d.Players)
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		view := PlayerView{Filter: c.Query("filter"), Sort: c.Query("sort"), Ratings: d.ratings()}
+		filters, sorts := playerViewOptions(d.FormFields)
+		best := ViewOption{Value: bestAvailableSort, Label: "Best available"}
+		if d.UseRatings {
+			best.Label = "Best available (by rating)"
+		}
+		sorts = append([]ViewOption{best}, sorts...)
+
+		c.HTML(http.StatusOK, "drafting.html", d.pageData(gin.H{
+			"organizer":     d.IsOrganizer(c),
+			"coverage":      d.RoleCoverage(),
+			"shownPlayers":  view.Apply(d.DraftPlayers),
+			"view":          view,
+			"filterOptions": filters,
+			"sortOptions":   sorts,
+		}))
 	})
 
 	// Handle the form submission for player selection & advance the draft turn
-	router.POST("/pick-player", func(c *gin.Context) {
-		currCaptain := draftOrder[currentCaptainIndex].Name
-		selectedPlayer := c.PostForm("selectedPlayer")
+	draft.POST("/pick-player", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		ctx := c.Request.Context()
 
-		AddPlayerToDraftTeam(tournamentID, teams, currCaptain,selectedPlayer)
+		if len(d.DraftOrder) == 0 {
+			c.Redirect(http.StatusFound, draftURL(d, ""))
+			return
+		}
 
-		// Get updated teams list
-		teams = GetTeams(tournamentID, players)
+		if d.Mode == auctionMode {
+			c.String(http.StatusBadRequest, "This is an auction draft, players are won by bidding.")
+			return
+		}
 
-		// Remove the selected player from the list
-		draftPlayers = RemoveDraftedPlayers(draftPlayers, selectedPlayer)
+		// Only the organizer, or the captain who's up with their own link, can pick
+		if !d.canPickNow(c) {
+			showError(c, http.StatusForbidden, fmt.Errorf("only the organizer or %v can make this pick", d.CurrentCaptain().Name))
+			return
+		}
 
-		if len(draftPlayers) == 0 {
-			c.Redirect(http.StatusFound, "/done")
+		// Picks are keyed by player ID so two players with the same name can't get mixed up
+		selectedPlayer, err := parseID(c.PostForm("selectedPlayer"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		if !checkRosterPick(c, d, float64(selectedPlayer)) {
 			return
 		}
 
-		// Advance the draft turn
-		advanceDraftTurn(draftOrder)
+		// Write the pick to HiveMind, remove the player from the pool and move on to the next captain
+		if _, err := d.PickPlayer(ctx, float64(selectedPlayer)); err != nil {
+			if errors.Is(err, errAlreadyDrafted) {
+				c.String(http.StatusBadRequest, "That player has already been drafted.")
+				return
+			}
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+		drafts.startPickClock(d)
 
-		// Re-render the drafting page with the updated current captain
-		c.HTML(http.StatusOK, "drafting.html", gin.H{
-			"selectedTournament": selectedTournament,
-			"captainCount": captainCount,
-			"remainingPlayerCount": remainingPlayerCount,
-			"draftOrder": draftOrder,
-			"draftPlayers": draftPlayers,
-			"currentCaptain": currCaptain,
-			"teams": teams,
-		})
+		if len(d.DraftPlayers) == 0 {
+			c.Redirect(http.StatusFound, draftURL(d, "done"))
+			return
+		}
+
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+	})
+
+	// Read-only views for the crowd and the stream. These are all GETs, so loadDraft never saves anything for them.
+	draft.GET("/spectate", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		c.HTML(http.StatusOK, "spectate.html", d.pageData(gin.H{
+			"state": d.State(),
+		}))
+	})
+
+	draft.GET("/overlay", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		c.HTML(http.StatusOK, "overlay.html", d.pageData(gin.H{
+			"state": d.State(),
+		}))
+	})
+
+	draft.GET("/state.json", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.JSON(http.StatusOK, d.State())
+	})
+
+	// A captain's own pick page, opened from their private link
+	captain := draft.Group("/captain/:token", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		link, ok := d.CaptainLinkByToken(c.Param("token"))
+		if !ok {
+			showError(c, http.StatusForbidden, fmt.Errorf("this captain link isn't valid for draft %v, ask the organizer for a new one", d.ID))
+			c.Abort()
+			return
+		}
+
+		c.Set("captainLink", link)
+		c.Next()
+	})
+
+	captain.GET("", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		link := c.MustGet("captainLink").(CaptainLink)
+
+		me, roster, myTurn := d.captainView(link)
+
+		var lot *AuctionLot
+		maxBid := 0
+		if d.Mode == auctionMode && d.Auction != nil {
+			lot = d.Auction.Lot
+			maxBid = d.MaxBid(link.CaptainID)
+		}
+
+		c.HTML(http.StatusOK, "captain.html", d.pageData(gin.H{
+			"captain":     me,
+			"captainLink": link,
+			"captainURL":  link.URL(d.ID),
+			"roster":      roster,
+			// Flag who's played with this captain's team, not whoever's picking
+			"recentTeammates": d.recentTeammates(roster),
+			"queue":           d.Queue(link.CaptainID),
+			"myTurn":          myTurn,
+			"finished":        d.Finished(),
+			"lot":             lot,
+			"maxBid":          maxBid,
+		}))
+	})
+
+	// Captains bid on the current lot from their own link, so the bid is always theirs
+	captain.POST("/bid", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		link := c.MustGet("captainLink").(CaptainLink)
+
+		if d.Mode != auctionMode {
+			c.String(http.StatusBadRequest, "This draft isn't an auction.")
+			return
+		}
+
+		amount, err := strconv.Atoi(c.PostForm("amount"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid bid")
+			return
+		}
+		if err := d.Bid(link.CaptainID, amount); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		drafts.scheduleLotClose(d)
+
+		c.Redirect(http.StatusFound, link.URL(d.ID))
+	})
+
+	// Picks from a captain link only go through on that captain's turn
+	captain.POST("/pick", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		link := c.MustGet("captainLink").(CaptainLink)
+
+		if _, _, myTurn := d.captainView(link); !myTurn {
+			showError(c, http.StatusForbidden, fmt.Errorf("it's not your turn, %v is picking", d.CurrentCaptain().Name))
+			return
+		}
+
+		selectedPlayer, err := parseID(c.PostForm("selectedPlayer"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		if !checkRosterPick(c, d, float64(selectedPlayer)) {
+			return
+		}
+
+		if _, err := d.PickPlayer(c.Request.Context(), float64(selectedPlayer)); err != nil {
+			if errors.Is(err, errAlreadyDrafted) {
+				c.String(http.StatusBadRequest, "That player has already been drafted.")
+				return
+			}
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+		drafts.startPickClock(d)
+
+		c.Redirect(http.StatusFound, link.URL(d.ID))
+	})
+
+	captain.POST("/queue/add", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		link := c.MustGet("captainLink").(CaptainLink)
+
+		playerID, err := parseID(c.PostForm("playerID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		if err := d.QueuePlayer(link.CaptainID, float64(playerID)); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Redirect(http.StatusFound, link.URL(d.ID))
+	})
+
+	captain.POST("/queue/remove", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		link := c.MustGet("captainLink").(CaptainLink)
+
+		playerID, err := parseID(c.PostForm("playerID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		d.UnqueuePlayer(link.CaptainID, float64(playerID))
+
+		c.Redirect(http.StatusFound, link.URL(d.ID))
+	})
+
+	// Pause or resume the pick clock
+	organizer.POST("/clock/pause", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		d.PauseClock()
+
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+	})
+
+	organizer.POST("/clock/resume", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		d.ResumeClock()
+		drafts.scheduleAutoPick(d)
+
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+	})
+
+	// Add a player to a captain's auto-pick queue
+	organizer.POST("/queue/add", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		captainID, err := parseID(c.PostForm("captainID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid captain")
+			return
+		}
+		playerID, err := parseID(c.PostForm("playerID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		if err := d.QueuePlayer(float64(captainID), float64(playerID)); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+	})
+
+	// Take a player off a captain's auto-pick queue
+	organizer.POST("/queue/remove", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		captainID, err := parseID(c.PostForm("captainID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid captain")
+			return
+		}
+		playerID, err := parseID(c.PostForm("playerID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		d.UnqueuePlayer(float64(captainID), float64(playerID))
+
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+	})
+
+	// Auction board route
+	draft.GET("/auction", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if d.Mode != auctionMode {
+			c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+			return
+		}
+		if d.Finished() {
+			c.Redirect(http.StatusFound, draftURL(d, "done"))
+			return
+		}
+
+		nominator, _ := d.Nominator()
+
+		c.HTML(http.StatusOK, "auction.html", d.pageData(gin.H{
+			"auction":   d.Auction,
+			"lot":       d.Auction.Lot,
+			"nominator": nominator,
+			"standings": d.Standings(),
+		}))
+	})
+
+	// Put a player up for bids for the captain whose turn it is to nominate
+	organizer.POST("/auction/nominate", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if d.Mode != auctionMode {
+			c.String(http.StatusBadRequest, "This draft isn't an auction.")
+			return
+		}
+
+		playerID, err := parseID(c.PostForm("selectedPlayer"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid player selection")
+			return
+		}
+		if err := d.Nominate(float64(playerID)); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		drafts.scheduleLotClose(d)
+
+		c.Redirect(http.StatusFound, draftURL(d, "auction"))
+	})
+
+	// Place a bid on the current lot for a captain bidding out loud. Captains bid for themselves from their own link.
+	organizer.POST("/auction/bid", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if d.Mode != auctionMode {
+			c.String(http.StatusBadRequest, "This draft isn't an auction.")
+			return
+		}
+
+		captainID, err := parseID(c.PostForm("captainID"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid captain")
+			return
+		}
+		amount, err := strconv.Atoi(c.PostForm("amount"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid bid")
+			return
+		}
+		if err := d.Bid(float64(captainID), amount); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		drafts.scheduleLotClose(d)
+
+		c.Redirect(http.StatusFound, draftURL(d, "auction"))
+	})
+
+	// Sell the current lot to the high bidder right away
+	organizer.POST("/auction/close", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if d.Mode != auctionMode || d.Auction.Lot == nil {
+			c.Redirect(http.StatusFound, draftURL(d, "auction"))
+			return
+		}
+
+		if _, err := d.CloseLot(c.Request.Context()); err != nil && !errors.Is(err, errAlreadyDrafted) {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		if d.Finished() {
+			c.Redirect(http.StatusFound, draftURL(d, "done"))
+			return
+		}
+		c.Redirect(http.StatusFound, draftURL(d, "auction"))
+	})
+
+	// Undo the most recent pick, or every pick back to the one posted in "toPick"
+	organizer.POST("/undo", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+		ctx := c.Request.Context()
+
+		if len(d.Picks) == 0 {
+			c.String(http.StatusBadRequest, "There are no picks to undo.")
+			return
+		}
+
+		var err error
+		if toPick := c.PostForm("toPick"); toPick != "" {
+			n, convErr := strconv.Atoi(toPick)
+			if convErr != nil || n < 1 || n > len(d.Picks) {
+				c.String(http.StatusBadRequest, "Invalid pick number")
+				return
+			}
+			_, err = d.UndoToPick(ctx, n)
+		} else {
+			_, err = d.UndoLastPick(ctx)
+		}
+		if err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+		drafts.startPickClock(d)
+		d.refreshTeams(ctx)
+
+		c.Redirect(http.StatusFound, draftURL(d, "drafting"))
+	})
+
+	// Skip captains and have the optimizer build balanced teams
+	organizer.POST("/balance", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		teamCount, err := strconv.Atoi(c.PostForm("teamCount"))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Invalid number of teams")
+			return
+		}
+
+		if err := d.ProposeBalancedTeams(c.Request.Context(), teamCount); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c.Redirect(http.StatusFound, draftURL(d, "balance"))
+	})
+
+	// Show the current balanced team proposal
+	draft.GET("/balance", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if d.Proposal == nil {
+			c.Redirect(http.StatusFound, draftURL(d, ""))
+			return
+		}
+
+		c.HTML(http.StatusOK, "balance.html", d.pageData(gin.H{
+			"proposal": d.Proposal,
+		}))
+	})
+
+	// Push the accepted proposal to HiveMind
+	organizer.POST("/balance/accept", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		if err := d.AcceptProposal(c.Request.Context()); err != nil {
+			showError(c, http.StatusBadGateway, err)
+			return
+		}
+
+		c.Redirect(http.StatusFound, draftURL(d, "done"))
+	})
+
+	// Download the rosters and pick history as csv, json or md
+	draft.GET("/export/:format", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		format := c.Param("format")
+		exportFormat, ok := exportFormats[format]
+		if !ok {
+			c.String(http.StatusBadRequest, "Unknown export format %q, use csv, json or md", format)
+			return
+		}
+
+		export := d.Export(splitFields(c.Query("fields")))
+
+		c.Header("Content-Type", exportFormat.ContentType)
+		if c.Query("download") != "0" {
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", exportFilename(export, format)))
+		}
+		if err := WriteExport(c.Writer, export, format); err != nil {
+			log.Printf("Draft %v: writing %v export: %v", d.ID, format, err)
+		}
 	})
 
 	// Final page route
-	router.GET("/done", func(c *gin.Context) {
-		c.HTML(http.StatusOK, "done.html", gin.H{
-			"selectedTournament": selectedTournament,
-			"teams": teams,
-		})
+	draft.GET("/done", func(c *gin.Context) {
+		d := c.MustGet("draft").(*Draft)
+
+		c.HTML(http.StatusOK, "done.html", d.pageData(gin.H{
+			"brokenPairs": d.BrokenPairRequests(),
+		}))
 	})
 
-	err := router.Run(":" + port)
-	if err != nil {
-		log.Fatalf("Failed to start the server: %v", err)
-	}
+	return router
 }
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imandradesign/hm-drafter/hivemind"
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+func TestMain(m *testing.M) {
+	// Templates and static files are loaded relative to the repo root
+	if err := os.Chdir("../.."); err != nil {
+		panic(err)
+	}
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// startFakeHiveMind points hm at a fake seeded from the repo's fixtures
+func startFakeHiveMind(t *testing.T) *hivemindtest.Server {
+	t.Helper()
+
+	fx, err := hivemindtest.LoadFixtures("fixtures/hivemind.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveFakeHiveMind(t, fx)
+}
+
+// serveFakeHiveMind points hm at a fake seeded with fx and gives the test a fresh draft registry. The fake is mounted the same way as hivemindtest.Start, but kept so tests can check what was written to it.
+func serveFakeHiveMind(t *testing.T, fx *hivemindtest.Fixtures) *hivemindtest.Server {
+	t.Helper()
+
+	fake := hivemindtest.NewServer(fx)
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", fake))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	oldHM, oldDrafts := hm, drafts
+	hm = hivemind.NewClient("", hivemind.WithBaseURL(server.URL+"/api"))
+	drafts = NewDraftRegistry(nil)
+	resetTournamentList()
+	t.Cleanup(resetTournamentList)
+	t.Cleanup(func() { hm, drafts = oldHM, oldDrafts })
+
+	return fake
+}
+
+// resetTournamentList throws away the cached tournament list, which would otherwise outlive the fake it came from
+func resetTournamentList() {
+	tournamentList.mu.Lock()
+	defer tournamentList.mu.Unlock()
+	tournamentList.started = time.Time{}
+}
+
+// testClient sends form posts to the router, keeping the cookies it's given like a browser
+type testClient struct {
+	t       *testing.T
+	router  *gin.Engine
+	cookies map[string]*http.Cookie
+}
+
+func (tc *testClient) do(method, path string, form url.Values) *httptest.ResponseRecorder {
+	tc.t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, cookie := range tc.cookies {
+		req.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	tc.router.ServeHTTP(w, req)
+	for _, cookie := range w.Result().Cookies() {
+		tc.cookies[cookie.Name] = cookie
+	}
+	return w
+}
+
+// post sends a form and fails the test unless it gets the wanted status
+func (tc *testClient) post(path string, form url.Values, want int) *httptest.ResponseRecorder {
+	tc.t.Helper()
+	w := tc.do(http.MethodPost, path, form)
+	if w.Code != want {
+		tc.t.Fatalf("POST %v: got status %v, want %v\n%v", path, w.Code, want, w.Body.String())
+	}
+	return w
+}
+
+// fakeTeamID looks up a team the fake has stored by name
+func fakeTeamID(t *testing.T, fake *hivemindtest.Server, name string) int {
+	t.Helper()
+	for _, team := range fake.Teams() {
+		if team.Name == name && team.Tournament == 104 {
+			return team.ID
+		}
+	}
+	t.Fatalf("the fake has no team called %v", name)
+	return 0
+}
+
+func TestDraftFlow(t *testing.T) {
+	fake := startFakeHiveMind(t)
+	organizer := &testClient{t: t, router: setupRouter(), cookies: map[string]*http.Cookie{}}
+	anonymous := &testClient{t: t, router: organizer.router, cookies: map[string]*http.Cookie{}}
+
+	// Start a draft for the fixture tournament, which signs the browser in as its organizer
+	w := organizer.post("/drafts", url.Values{"tournamentID": {"104"}}, http.StatusFound)
+	base := w.Header().Get("Location")
+	d := drafts.Get(strings.TrimPrefix(base, "/drafts/"))
+	if d == nil {
+		t.Fatalf("no draft at %v", base)
+	}
+
+	organizer.post(base+"/confirm-captains", url.Values{"selectedPlayers": {"Alex R", "Bea T"}}, http.StatusFound)
+	organizer.post(base+"/add-team", url.Values{"teamAddition": {"Red"}}, http.StatusFound)
+	organizer.post(base+"/add-team", url.Values{"teamAddition": {"Blue"}}, http.StatusFound)
+
+	red, blue := fakeTeamID(t, fake, "Red"), fakeTeamID(t, fake, "Blue")
+	organizer.post(base+"/assign-captain", url.Values{"captainID": {"5001"}, "teamID": {strconv.Itoa(red)}}, http.StatusFound)
+	organizer.post(base+"/assign-captain", url.Values{"captainID": {"5002"}, "teamID": {strconv.Itoa(blue)}}, http.StatusFound)
+	if got := fake.PlayerTeam(5001); got != red {
+		t.Errorf("Alex R is on team %v in HiveMind, want Red (%v)", got, red)
+	}
+	organizer.post(base+"/confirm-teams", url.Values{"format": {"snake"}}, http.StatusFound)
+
+	captainTeam := map[float64]int{5001: red, 5002: blue}
+	firstTeam := captainTeam[d.CurrentCaptain().ID]
+
+	// Only the organizer, or the captain who's up, can pick
+	anonymous.post(base+"/pick-player", url.Values{"selectedPlayer": {"5003"}}, http.StatusForbidden)
+	if got := fake.PlayerTeam(5003); got != 0 {
+		t.Fatalf("a refused pick put player 5003 on team %v", got)
+	}
+
+	organizer.post(base+"/pick-player", url.Values{"selectedPlayer": {"5003"}}, http.StatusFound)
+	if got := fake.PlayerTeam(5003); got != firstTeam {
+		t.Fatalf("player 5003 is on team %v in HiveMind, want %v", got, firstTeam)
+	}
+	organizer.post(base+"/pick-player", url.Values{"selectedPlayer": {"5003"}}, http.StatusBadRequest)
+
+	// Captains can't change once someone has been drafted
+	organizer.post(base+"/confirm-captains", url.Values{"selectedPlayers": {"Alex R", "Cam P"}}, http.StatusBadRequest)
+
+	// Undoing takes the player back off their HiveMind team and puts them back in the pool
+	organizer.post(base+"/undo", nil, http.StatusFound)
+	if got := fake.PlayerTeam(5003); got != 0 {
+		t.Errorf("player 5003 is still on team %v after the undo", got)
+	}
+	if len(d.Picks) != 0 {
+		t.Errorf("%v picks left after undoing the only one", len(d.Picks))
+	}
+	if _, ok := d.poolPlayer(5003); !ok {
+		t.Error("player 5003 isn't back in the pool after the undo")
+	}
+	if captain := d.CurrentCaptain(); captainTeam[captain.ID] != firstTeam {
+		t.Errorf("%v is up after the undo, want the first captain again", captain.Name)
+	}
+
+	// Draft everyone else, then the done page shows the final teams
+	for !d.Finished() {
+		next := strconv.FormatFloat(d.DraftPlayers[0].ID, 'f', -1, 64)
+		organizer.post(base+"/pick-player", url.Values{"selectedPlayer": {next}}, http.StatusFound)
+	}
+	if w := organizer.do(http.MethodGet, base+"/done", nil); w.Code != http.StatusOK {
+		t.Fatalf("GET %v/done: got status %v", base, w.Code)
+	}
+
+	onTeam := map[int]int{}
+	for id := 5001; id <= 5018; id++ {
+		team := fake.PlayerTeam(id)
+		if team != red && team != blue {
+			t.Errorf("player %v ended up on team %v, want Red or Blue", id, team)
+		}
+		onTeam[team]++
+	}
+	if onTeam[red] != 9 || onTeam[blue] != 9 {
+		t.Errorf("teams ended up with %v on Red and %v on Blue, want 9 each", onTeam[red], onTeam[blue])
+	}
+}
+
+func TestOrganizerOnlyRoutes(t *testing.T) {
+	startFakeHiveMind(t)
+	organizer := &testClient{t: t, router: setupRouter(), cookies: map[string]*http.Cookie{}}
+	anonymous := &testClient{t: t, router: organizer.router, cookies: map[string]*http.Cookie{}}
+
+	w := organizer.post("/drafts", url.Values{"tournamentID": {"104"}}, http.StatusFound)
+	base := w.Header().Get("Location")
+	d := drafts.Get(strings.TrimPrefix(base, "/drafts/"))
+
+	routes := []struct {
+		method string
+		path   string
+		form   url.Values
+	}{
+		{method: http.MethodGet, path: "/captain-links"},
+		{method: http.MethodPost, path: "/close"},
+		{method: http.MethodPost, path: "/undo"},
+		{method: http.MethodPost, path: "/clock/pause"},
+		{method: http.MethodPost, path: "/clock/resume"},
+		{method: http.MethodPost, path: "/queue/add", form: url.Values{"captainID": {"5001"}, "playerID": {"5003"}}},
+		{method: http.MethodPost, path: "/queue/remove", form: url.Values{"captainID": {"5001"}, "playerID": {"5003"}}},
+		{method: http.MethodPost, path: "/balance", form: url.Values{"teams": {"2"}}},
+		{method: http.MethodPost, path: "/balance/accept"},
+		{method: http.MethodPost, path: "/ratings"},
+		{method: http.MethodPost, path: "/confirm-captains", form: url.Values{"selectedPlayers": {"Alex R", "Bea T"}}},
+		{method: http.MethodPost, path: "/add-team", form: url.Values{"teamAddition": {"Red"}}},
+		{method: http.MethodPost, path: "/remove-team", form: url.Values{"teamID": {"1"}}},
+		{method: http.MethodPost, path: "/assign-captain", form: url.Values{"captainID": {"5001"}, "teamID": {"1"}}},
+		{method: http.MethodPost, path: "/confirm-teams", form: url.Values{"format": {"snake"}}},
+		{method: http.MethodPost, path: "/auction/nominate", form: url.Values{"playerID": {"5003"}}},
+		{method: http.MethodPost, path: "/auction/bid", form: url.Values{"captainID": {"5001"}, "amount": {"1"}}},
+		{method: http.MethodPost, path: "/auction/close"},
+		{method: http.MethodPost, path: "/roster-rules", form: url.Values{"rules": {"at least 1 roles: Queen"}}},
+		{method: http.MethodPost, path: "/pairs/add", form: url.Values{"kind": {"together"}, "playerA": {"5001"}, "playerB": {"5002"}}},
+		{method: http.MethodPost, path: "/pairs/remove", form: url.Values{"index": {"0"}}},
+		{method: http.MethodPost, path: "/mix-it-up", form: url.Values{"mixItUp": {"on"}}},
+		{method: http.MethodPost, path: "/board-fields", form: url.Values{"fields": {"skill"}}},
+	}
+
+	for _, route := range routes {
+		if w := anonymous.do(route.method, base+route.path, route.form); w.Code != http.StatusForbidden {
+			t.Errorf("%v %v without the organizer cookie: got status %v, want %v", route.method, route.path, w.Code, http.StatusForbidden)
+		}
+	}
+
+	// Nothing the refused requests asked for happened
+	if drafts.Get(d.ID) == nil {
+		t.Error("the draft was closed")
+	}
+	if len(d.Captains) != 0 || len(d.Teams) != 0 {
+		t.Errorf("the draft has %v captains and %v teams, want none", len(d.Captains), len(d.Teams))
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// mixHistoryTournaments is how many of the scene's previous tournaments count as "recently"
+	mixHistoryTournaments = 6
+
+	// mixHistoryTTL is how long a scene's co-play history is reused before HiveMind is asked again
+	mixHistoryTTL = 30 * time.Minute
+
+	// mixPenalty is what each repeat teammate pairing costs a balanced team, about as much as a fair skill gap so mixing never outweighs a missing role
+	mixPenalty = 0.5
+)
+
+var mixHistories = struct {
+	mu      sync.Mutex
+	cached  map[string]*CoPlayHistory
+	fetched map[string]time.Time
+}{cached: make(map[string]*CoPlayHistory), fetched: make(map[string]time.Time)}
+
+// CoPlayHistory counts how often players have been teammates in a scene's recent tournaments. Players are matched by name since HiveMind gives them a new ID for every tournament.
+type CoPlayHistory struct {
+	Tournaments []string          // Names of the tournaments it covers, newest first
+	Pairs       map[string]CoPlay `json:",omitempty"` // Keyed by coPlayKey
+}
+
+// CoPlay is how often two players were on the same team
+type CoPlay struct {
+	Count int
+	Last  string // The most recent tournament they were teammates in
+}
+
+// nameKey normalizes a player's name for matching across tournaments, ignoring case and spacing
+func nameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// coPlayKey is the same for two players whichever way round they're given
+func coPlayKey(a, b string) string {
+	a, b = nameKey(a), nameKey(b)
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// Together returns how often two players were teammates, and in which tournament they last were
+func (h *CoPlayHistory) Together(a, b string) CoPlay {
+	if h == nil || nameKey(a) == nameKey(b) {
+		return CoPlay{}
+	}
+	return h.Pairs[coPlayKey(a, b)]
+}
+
+// Repeats counts the times player has already been on a team with anyone in team
+func (h *CoPlayHistory) Repeats(team []Player, player Player) (repeats int) {
+	for _, teammate := range team {
+		repeats += h.Together(teammate.Name, player.Name).Count
+	}
+	return repeats
+}
+
+// teamRepeats counts the repeat pairings within a team
+func (h *CoPlayHistory) teamRepeats(team []Player) (repeats int) {
+	if h == nil {
+		return 0
+	}
+	for i := range team {
+		repeats += h.Repeats(team[:i], team[i])
+	}
+	return repeats
+}
+
+// LoadCoPlayHistory builds the co-play history from the rosters of the scene's tournaments before the given date (YYYY-MM-DD), leaving out the tournament being drafted. The lock is only held to read and update the cache, never while HiveMind is asked.
+func LoadCoPlayHistory(ctx context.Context, scene, before string, excludeID string) (*CoPlayHistory, error) {
+	cacheKey := scene + "/" + before + "/" + excludeID
+
+	mixHistories.mu.Lock()
+	cached, ok := mixHistories.cached[cacheKey]
+	fresh := ok && time.Since(mixHistories.fetched[cacheKey]) < mixHistoryTTL
+	mixHistories.mu.Unlock()
+	if fresh {
+		return cached, nil
+	}
+
+	// Newest first, walking pages until enough tournaments before the date have been found
+	history := &CoPlayHistory{Pairs: make(map[string]CoPlay)}
+	for page, pages := 1, 1; page <= pages && len(history.Tournaments) < mixHistoryTournaments; page++ {
+		results, err := SearchTournaments(ctx, TournamentFilter{Scene: scene, When: "past", Page: page})
+		if err != nil {
+			return nil, err
+		}
+		pages = results.Pages
+
+		for _, tournament := range results.Tournaments {
+			if len(history.Tournaments) == mixHistoryTournaments {
+				break
+			}
+			if strconv.Itoa(tournament.ID) == excludeID || (before != "" && tournamentDate(tournament) >= before) {
+				continue
+			}
+
+			rosters, err := tournamentRosters(ctx, tournament.ID)
+			if err != nil {
+				return nil, fmt.Errorf("fetching the rosters of %v: %w", tournament.Name, err)
+			}
+			history.add(tournament.Name, rosters)
+		}
+	}
+
+	log.Printf("Loaded co-play history for %v from %v tournaments (%v pairings)", scene, len(history.Tournaments), len(history.Pairs))
+	mixHistories.mu.Lock()
+	mixHistories.cached[cacheKey] = history
+	mixHistories.fetched[cacheKey] = time.Now()
+	mixHistories.mu.Unlock()
+	return history, nil
+}
+
+// add counts every pair of teammates in a tournament's rosters. Tournaments are added newest first.
+func (h *CoPlayHistory) add(tournament string, rosters map[int][]string) {
+	h.Tournaments = append(h.Tournaments, tournament)
+
+	for _, roster := range rosters {
+		for i := range roster {
+			for j := i + 1; j < len(roster); j++ {
+				key := coPlayKey(roster[i], roster[j])
+				pair := h.Pairs[key]
+				pair.Count++
+				// Tournaments are newest first, so the first one seen is the last they played together
+				if pair.Last == "" {
+					pair.Last = tournament
+				}
+				h.Pairs[key] = pair
+			}
+		}
+	}
+}
+
+// tournamentRosters returns the names of the players on each of a tournament's teams, keyed by team ID
+func tournamentRosters(ctx context.Context, tournamentID int) (map[int][]string, error) {
+	teams, err := hm.Teams(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	players, err := hm.Players(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	rosters := make(map[int][]string)
+	for _, team := range teams {
+		rosters[team.ID] = nil
+	}
+	for _, player := range players {
+		teamID := int(safeFloat(player["team"]))
+		if _, ok := rosters[teamID]; ok {
+			rosters[teamID] = append(rosters[teamID], safeString(player["name"]))
+		}
+	}
+	return rosters, nil
+}
+
+// SetMixItUp turns mix it up mode on, loading who's played together in the scene's recent tournaments, or off
+func (d *Draft) SetMixItUp(ctx context.Context, on bool) error {
+	if !on {
+		d.MixItUp = false
+		d.CoPlay = nil
+		log.Printf("Draft %v: mix it up off", d.ID)
+		d.publish("reload", nil)
+		return nil
+	}
+
+	if d.Source != "" && d.Source != hivemindSource {
+		return fmt.Errorf("mix it up needs a HiveMind tournament to look up past rosters")
+	}
+
+	date := ""
+	if len(d.SelectedTournament) > 2 && len(d.SelectedTournament[2]) >= 10 {
+		date = d.SelectedTournament[2][:10]
+	}
+	history, err := LoadCoPlayHistory(ctx, d.sceneName(), date, d.TournamentID)
+	if err != nil {
+		return err
+	}
+
+	d.MixItUp = true
+	d.CoPlay = history
+	log.Printf("Draft %v: mix it up on", d.ID)
+	d.publish("reload", nil)
+	return nil
+}
+
+// coPlay is the history auto-picks and balancing should mix away from, or nil if mix it up is off
+func (d *Draft) coPlay() *CoPlayHistory {
+	if !d.MixItUp {
+		return nil
+	}
+	return d.CoPlay
+}
+
+// RecentNotes looks up who on a team each player has played with recently
+type RecentNotes map[float64]string
+
+// For returns the note for a player's card, or "" if they haven't played with anyone on the team
+func (n RecentNotes) For(playerID float64) string {
+	return n[playerID]
+}
+
+// recentTeammates notes, for each player in the pool, who on team they've played with in the scene's recent tournaments
+func (d *Draft) recentTeammates(team []Player) RecentNotes {
+	history := d.coPlay()
+	if history == nil {
+		return nil
+	}
+
+	notes := make(RecentNotes)
+	for _, player := range d.DraftPlayers {
+		var with []string
+		for _, teammate := range team {
+			if together := history.Together(teammate.Name, player.Name); together.Count > 0 {
+				with = append(with, fmt.Sprintf("%v (%dx, last at %v)", teammate.Name, together.Count, together.Last))
+			}
+		}
+		if len(with) > 0 {
+			sort.Strings(with)
+			notes[player.ID] = strings.Join(with, ", ")
+		}
+	}
+	return notes
+}
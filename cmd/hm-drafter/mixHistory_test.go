@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+// mixFixtures is n weekly past tournaments in the mixtest scene, where A and B are teammates every time and everyone else plays once
+func mixFixtures(n int) *hivemindtest.Fixtures {
+	fx := &hivemindtest.Fixtures{}
+	start := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		team := 1000 + i
+		fx.Tournaments = append(fx.Tournaments, map[string]interface{}{
+			"id": i, "name": fmt.Sprintf("Week %d", i), "date": mixDate(start, i), "scene_name": "mixtest",
+		})
+		fx.Teams = append(fx.Teams, hivemindtest.Team{ID: team, Name: "Team", Tournament: i})
+		for j, name := range []string{"A", "B", fmt.Sprintf("Player %d", i)} {
+			fx.Players = append(fx.Players, map[string]interface{}{"id": i*10 + j, "name": name, "team": team, "tournament": i})
+		}
+	}
+	return fx
+}
+
+// mixDate is the date of week i of mixFixtures
+func mixDate(start time.Time, i int) string {
+	return start.AddDate(0, 0, 7*(i-1)).Format("2006-01-02")
+}
+
+// weeks names tournaments from mixFixtures, in the order given
+func weeks(ids ...int) (names []string) {
+	for _, id := range ids {
+		names = append(names, fmt.Sprintf("Week %d", id))
+	}
+	return names
+}
+
+func TestLoadCoPlayHistory(t *testing.T) {
+	start := time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		tournaments int
+		before      int // Only weeks before this one count, 0 for no cutoff
+		exclude     int
+		want        []string
+	}{
+		{name: "newest six", tournaments: 10, want: weeks(10, 9, 8, 7, 6, 5)},
+		{name: "leaving out the drafted tournament", tournaments: 10, exclude: 9, want: weeks(10, 8, 7, 6, 5, 4)},
+		{name: "before a date", tournaments: 10, before: 5, want: weeks(4, 3, 2, 1)},
+		// The newest page of 20 is all after the date, so the lookback comes from later pages
+		{name: "past the first page", tournaments: 45, before: 12, want: weeks(11, 10, 9, 8, 7, 6)},
+		{name: "across pages", tournaments: 45, before: 29, want: weeks(28, 27, 26, 25, 24, 23)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveFakeHiveMind(t, mixFixtures(tt.tournaments))
+			// Every case has the same scene, so start each without the last one's cached history
+			mixHistories.mu.Lock()
+			mixHistories.cached, mixHistories.fetched = make(map[string]*CoPlayHistory), make(map[string]time.Time)
+			mixHistories.mu.Unlock()
+
+			before := ""
+			if tt.before > 0 {
+				before = mixDate(start, tt.before)
+			}
+			history, err := LoadCoPlayHistory(context.Background(), "mixtest", before, strconv.Itoa(tt.exclude))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(history.Tournaments, tt.want) {
+				t.Errorf("covers %v, want %v", history.Tournaments, tt.want)
+			}
+			together := history.Together("a", "B")
+			if together.Count != len(tt.want) || together.Last != tt.want[0] {
+				t.Errorf("A and B played together %+v, want %v times, last in %v", together, len(tt.want), tt.want[0])
+			}
+			if got := history.Together("A", "Player 1").Count; got > 1 {
+				t.Errorf("A and Player 1 played together %v times, want at most once", got)
+			}
+		})
+	}
+}
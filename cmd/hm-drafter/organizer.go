@@ -0,0 +1,69 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// organizerCookieAge is how long a browser stays signed in as a draft's organizer, long enough to cover a tournament day
+const organizerCookieAge = 7 * 24 * 60 * 60
+
+// organizerCookie is the cookie holding the organizer key for one draft
+func organizerCookie(d *Draft) string {
+	return "organizer-" + d.ID
+}
+
+// OrganizerURL is the secret link that signs a browser in as the draft's organizer, for a second laptop or after cookies are cleared
+func (d *Draft) OrganizerURL() string {
+	return "/drafts/" + d.ID + "/organize/" + d.OrganizerKey
+}
+
+// signInOrganizer marks the browser as the draft's organizer
+func signInOrganizer(c *gin.Context, d *Draft) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(organizerCookie(d), d.OrganizerKey, organizerCookieAge, "/drafts/"+d.ID, "", false, true)
+}
+
+// organizerKeyMatches checks a key from a cookie or link against the draft's
+func (d *Draft) organizerKeyMatches(key string) bool {
+	return d.OrganizerKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(d.OrganizerKey)) == 1
+}
+
+// IsOrganizer reports whether the request comes from a browser signed in as the draft's organizer
+func (d *Draft) IsOrganizer(c *gin.Context) bool {
+	key, err := c.Cookie(organizerCookie(d))
+	return err == nil && d.organizerKeyMatches(key)
+}
+
+// requireOrganizer turns away anyone who isn't signed in as the draft's organizer. It runs after loadDraft.
+func requireOrganizer(c *gin.Context) {
+	d := c.MustGet("draft").(*Draft)
+	if !d.IsOrganizer(c) {
+		showError(c, http.StatusForbidden, fmt.Errorf("only the organizer of draft %v can do that, open the organizer link on this device first", d.ID))
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// canPickNow reports whether the request may make the current pick: the organizer always can, a captain only with their own link's token on their turn
+func (d *Draft) canPickNow(c *gin.Context) bool {
+	if d.IsOrganizer(c) {
+		return true
+	}
+	link, ok := d.CaptainLinkByToken(c.PostForm("captainToken"))
+	return ok && link.CaptainID == d.peekCaptain().ID
+}
+
+// ensureOrganizerKey gives drafts saved before organizer keys existed one, logging the link so the organizer can sign in
+func ensureOrganizerKey(d *Draft) {
+	if d.OrganizerKey != "" {
+		return
+	}
+	d.OrganizerKey = randomToken(16)
+	log.Printf("Draft %v had no organizer key, sign in with %v", d.ID, d.OrganizerURL())
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Kinds of pair request
+const (
+	togetherRequest = "together"
+	apartRequest    = "apart"
+)
+
+// PairRequest asks for two players to be put on the same team, or kept off the same team
+type PairRequest struct {
+	Kind    string // "together" or "apart"
+	PlayerA float64
+	NameA   string
+	PlayerB float64
+	NameB   string
+	Note    string `json:",omitempty"` // Why, if the organizer wants to remember
+}
+
+// Involves reports whether the request is about the player
+func (p PairRequest) Involves(playerID float64) bool {
+	return p.PlayerA == playerID || p.PlayerB == playerID
+}
+
+// Other returns the ID and name of the player the request pairs playerID with
+func (p PairRequest) Other(playerID float64) (float64, string) {
+	if p.PlayerA == playerID {
+		return p.PlayerB, p.NameB
+	}
+	return p.PlayerA, p.NameA
+}
+
+// samePair reports whether two requests are about the same two players, whichever way round
+func (p PairRequest) samePair(other PairRequest) bool {
+	return (p.PlayerA == other.PlayerA && p.PlayerB == other.PlayerB) || (p.PlayerA == other.PlayerB && p.PlayerB == other.PlayerA)
+}
+
+func (p PairRequest) String() string {
+	if p.Kind == apartRequest {
+		return fmt.Sprintf("%v and %v apart", p.NameA, p.NameB)
+	}
+	return fmt.Sprintf("%v and %v together", p.NameA, p.NameB)
+}
+
+// Broken reports whether teams has the request broken. Players who aren't on a team yet don't break anything.
+func (p PairRequest) Broken(teamOf map[float64]int) bool {
+	a, b := teamOf[p.PlayerA], teamOf[p.PlayerB]
+	if a == 0 || b == 0 {
+		return false
+	}
+	if p.Kind == apartRequest {
+		return a == b
+	}
+	return a != b
+}
+
+// pairBook caches each tournament's pair requests in front of the store
+type pairBook struct {
+	mu       sync.Mutex
+	requests map[string][]PairRequest
+}
+
+// PairRequests returns a tournament's pair requests
+func (r *DraftRegistry) PairRequests(tournament string) []PairRequest {
+	r.pairs.mu.Lock()
+	defer r.pairs.mu.Unlock()
+
+	if r.pairs.requests == nil {
+		r.pairs.requests = make(map[string][]PairRequest)
+	}
+	if requests, ok := r.pairs.requests[tournament]; ok {
+		return requests
+	}
+
+	requests, err := r.store.LoadPairs(tournament)
+	if err != nil {
+		log.Printf("Failed to load pair requests for %v: %v", tournament, err)
+		return nil
+	}
+	r.pairs.requests[tournament] = requests
+	return requests
+}
+
+// SetPairRequests saves a tournament's pair requests
+func (r *DraftRegistry) SetPairRequests(tournament string, requests []PairRequest) error {
+	if err := r.store.SavePairs(tournament, requests); err != nil {
+		return err
+	}
+
+	r.pairs.mu.Lock()
+	defer r.pairs.mu.Unlock()
+	if r.pairs.requests == nil {
+		r.pairs.requests = make(map[string][]PairRequest)
+	}
+	r.pairs.requests[tournament] = requests
+	return nil
+}
+
+// pairKey is what the draft's pair requests are stored under: its HiveMind tournament, so every draft of the tournament shares them, or the draft itself for imported players
+func (d *Draft) pairKey() string {
+	if d.TournamentID != "" {
+		return d.TournamentID
+	}
+	return "draft-" + d.ID
+}
+
+// PairRequests returns the pair requests for the draft's tournament
+func (d *Draft) PairRequests() []PairRequest {
+	return drafts.PairRequests(d.pairKey())
+}
+
+// playerByID finds one of the draft's players
+func (d *Draft) playerByID(playerID float64) (Player, bool) {
+	for _, player := range d.Players {
+		if player.ID == playerID {
+			return player, true
+		}
+	}
+	return Player{}, false
+}
+
+// AddPairRequest records that two players want to play together or apart. A new request for the same two players replaces the old one.
+func (d *Draft) AddPairRequest(kind string, playerA, playerB float64, note string) error {
+	if kind != togetherRequest && kind != apartRequest {
+		return fmt.Errorf("unknown pair request %q, expected together or apart", kind)
+	}
+	if playerA == playerB {
+		return fmt.Errorf("pick two different players")
+	}
+	a, okA := d.playerByID(playerA)
+	b, okB := d.playerByID(playerB)
+	if !okA || !okB {
+		return fmt.Errorf("both players need to be registered for this tournament")
+	}
+
+	request := PairRequest{Kind: kind, PlayerA: a.ID, NameA: a.Name, PlayerB: b.ID, NameB: b.Name, Note: note}
+	requests := []PairRequest{}
+	for _, existing := range d.PairRequests() {
+		if !existing.samePair(request) {
+			requests = append(requests, existing)
+		}
+	}
+	requests = append(requests, request)
+
+	if err := drafts.SetPairRequests(d.pairKey(), requests); err != nil {
+		return fmt.Errorf("saving pair requests: %w", err)
+	}
+	log.Printf("Draft %v: %v", d.ID, request)
+
+	// Player cards show the requests
+	d.publish("reload", nil)
+	return nil
+}
+
+// RemovePairRequest drops the request between two players
+func (d *Draft) RemovePairRequest(playerA, playerB float64) error {
+	target := PairRequest{PlayerA: playerA, PlayerB: playerB}
+	requests := []PairRequest{}
+	for _, existing := range d.PairRequests() {
+		if !existing.samePair(target) {
+			requests = append(requests, existing)
+		}
+	}
+
+	if err := drafts.SetPairRequests(d.pairKey(), requests); err != nil {
+		return fmt.Errorf("saving pair requests: %w", err)
+	}
+	log.Printf("Draft %v: removed the pair request between %v and %v", d.ID, formatID(playerA), formatID(playerB))
+
+	d.publish("reload", nil)
+	return nil
+}
+
+// PairNote is one pair request as shown on a player's card
+type PairNote struct {
+	Kind  string
+	Other string
+}
+
+// PairNotes looks up the pair requests on each player's card
+type PairNotes map[float64][]PairNote
+
+// For returns the notes for a player's card
+func (n PairNotes) For(playerID float64) []PairNote {
+	return n[playerID]
+}
+
+// pairNotes indexes the draft's pair requests by player
+func (d *Draft) pairNotes() PairNotes {
+	notes := make(PairNotes)
+	for _, request := range d.PairRequests() {
+		notes[request.PlayerA] = append(notes[request.PlayerA], PairNote{Kind: request.Kind, Other: request.NameB})
+		notes[request.PlayerB] = append(notes[request.PlayerB], PairNote{Kind: request.Kind, Other: request.NameA})
+	}
+	return notes
+}
+
+// teamOf maps each player on a team to their team
+func teamOf(teams [][]Player) map[float64]int {
+	on := make(map[float64]int)
+	for i, team := range teams {
+		for _, player := range team {
+			on[player.ID] = i + 1
+		}
+	}
+	return on
+}
+
+// brokenPairs returns the requests teams break
+func brokenPairs(requests []PairRequest, teams [][]Player) (broken []PairRequest) {
+	on := teamOf(teams)
+	for _, request := range requests {
+		if request.Broken(on) {
+			broken = append(broken, request)
+		}
+	}
+	return broken
+}
+
+// BrokenPairRequests returns the requests the draft's teams break
+func (d *Draft) BrokenPairRequests() []PairRequest {
+	var teams [][]Player
+	for _, team := range d.Teams {
+		teams = append(teams, team.Players)
+	}
+	return brokenPairs(d.PairRequests(), teams)
+}
+
+// pairFit scores adding player to team: +1 for each teammate they asked to play with, -1 for each they asked to be kept apart from
+func pairFit(requests []PairRequest, team []Player, player Player) (fit int) {
+	for _, request := range requests {
+		if !request.Involves(player.ID) {
+			continue
+		}
+		other, _ := request.Other(player.ID)
+		for _, teammate := range team {
+			if teammate.ID != other {
+				continue
+			}
+			if request.Kind == apartRequest {
+				fit--
+			} else {
+				fit++
+			}
+		}
+	}
+	return fit
+}
+
+// captainRoster returns the players on a captain's team
+func (d *Draft) captainRoster(captain Captain) []Player {
+	teamID := CaptainTeamID(d.Teams, captain)
+	for _, team := range d.Teams {
+		if team.ID == teamID {
+			return team.Players
+		}
+	}
+	return nil
+}
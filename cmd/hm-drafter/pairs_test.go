@@ -0,0 +1,206 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// pairsDraft is a local draft of 6 players and 2 captains, with a fresh registry to keep its pair requests in
+func pairsDraft(t *testing.T) *Draft {
+	t.Helper()
+	oldDrafts := drafts
+	drafts = NewDraftRegistry(nil)
+	t.Cleanup(func() { drafts = oldDrafts })
+	return localDraft(t, 6, 2)
+}
+
+// together and apart build requests between numbered test players
+func together(a, b float64) PairRequest { return pairRequest(togetherRequest, a, b) }
+func apart(a, b float64) PairRequest    { return pairRequest(apartRequest, a, b) }
+
+func pairRequest(kind string, a, b float64) PairRequest {
+	return PairRequest{Kind: kind, PlayerA: a, NameA: "Player " + formatID(a), PlayerB: b, NameB: "Player " + formatID(b)}
+}
+
+func TestAddPairRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []PairRequest
+		kind     string
+		a, b     float64
+		want     []PairRequest
+		wantErr  bool
+	}{
+		{name: "together", kind: togetherRequest, a: 3, b: 4, want: []PairRequest{together(3, 4)}},
+		{name: "apart", existing: []PairRequest{together(3, 4)}, kind: apartRequest, a: 5, b: 6, want: []PairRequest{together(3, 4), apart(5, 6)}},
+		{name: "replaces a request for the same players", existing: []PairRequest{together(3, 4), apart(5, 6)}, kind: apartRequest, a: 4, b: 3, want: []PairRequest{apart(5, 6), apart(4, 3)}},
+		{name: "captains can ask too", kind: apartRequest, a: 1, b: 2, want: []PairRequest{apart(1, 2)}},
+		{name: "unknown kind", kind: "nearby", a: 3, b: 4, wantErr: true},
+		{name: "the same player twice", kind: togetherRequest, a: 3, b: 3, wantErr: true},
+		{name: "a player who isn't registered", kind: togetherRequest, a: 3, b: 9, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := pairsDraft(t)
+			if err := drafts.SetPairRequests(d.pairKey(), tt.existing); err != nil {
+				t.Fatal(err)
+			}
+			_, ch, _ := d.events.Subscribe(d.events.LastID())
+			defer d.events.Unsubscribe(ch)
+
+			err := d.AddPairRequest(tt.kind, tt.a, tt.b, "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if got := d.PairRequests(); !reflect.DeepEqual(got, tt.existing) {
+					t.Errorf("a request that failed changed the requests to %v", got)
+				}
+				return
+			}
+			if got := d.PairRequests(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got requests %v, want %v", got, tt.want)
+			}
+			if event := <-ch; event.Type != "reload" {
+				t.Errorf("got a %v event, want the player cards reloaded", event.Type)
+			}
+		})
+	}
+}
+
+func TestRemovePairRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b float64
+		want []PairRequest
+	}{
+		{name: "as it was asked", a: 3, b: 4, want: []PairRequest{apart(5, 6)}},
+		{name: "the other way round", a: 6, b: 5, want: []PairRequest{together(3, 4)}},
+		{name: "a pair without a request", a: 3, b: 5, want: []PairRequest{together(3, 4), apart(5, 6)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := pairsDraft(t)
+			if err := drafts.SetPairRequests(d.pairKey(), []PairRequest{together(3, 4), apart(5, 6)}); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.RemovePairRequest(tt.a, tt.b); err != nil {
+				t.Fatal(err)
+			}
+			if got := d.PairRequests(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got requests %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairRequestsShared(t *testing.T) {
+	d := pairsDraft(t)
+	d.TournamentID = "104"
+	if err := d.AddPairRequest(togetherRequest, 3, 4, "carpooling"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another draft of the same tournament sees them, one of imported players doesn't
+	other := localDraft(t, 6, 2)
+	other.ID, other.TournamentID = "other", "104"
+	imported := localDraft(t, 6, 2)
+	if got := other.PairRequests(); len(got) != 1 || got[0].Note != "carpooling" {
+		t.Errorf("another draft of the tournament got %v, want the request with its note", got)
+	}
+	if got := imported.PairRequests(); len(got) != 0 {
+		t.Errorf("a draft of imported players got %v, want none", got)
+	}
+
+	// They outlive the registry's cache
+	drafts = NewDraftRegistry(drafts.store)
+	if got := other.PairRequests(); len(got) != 1 {
+		t.Errorf("got %v from the store, want the saved request", got)
+	}
+}
+
+func TestBrokenPairs(t *testing.T) {
+	players := make([]Player, 7)
+	for i := range players {
+		players[i] = Player{ID: float64(i)}
+	}
+	teams := [][]Player{{players[1], players[3]}, {players[2], players[4]}}
+
+	tests := []struct {
+		name    string
+		request PairRequest
+		broken  bool
+	}{
+		{name: "together on the same team", request: together(1, 3)},
+		{name: "together on different teams", request: together(1, 4), broken: true},
+		{name: "apart on different teams", request: apart(3, 4)},
+		{name: "apart on the same team", request: apart(2, 4), broken: true},
+		{name: "one still to be picked", request: together(1, 5)},
+		{name: "both still to be picked", request: apart(5, 6)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.request.Broken(teamOf(teams)); got != tt.broken {
+				t.Errorf("got broken %v, want %v", got, tt.broken)
+			}
+			got := brokenPairs([]PairRequest{tt.request}, teams)
+			if (len(got) == 1) != tt.broken {
+				t.Errorf("brokenPairs got %v", got)
+			}
+		})
+	}
+}
+
+func TestPairFit(t *testing.T) {
+	requests := []PairRequest{together(3, 1), together(3, 4), apart(5, 3), apart(1, 6)}
+	player := func(id float64) Player { return Player{ID: id} }
+
+	tests := []struct {
+		name   string
+		team   []Player
+		player float64
+		want   int
+	}{
+		{name: "no requests", team: []Player{player(1), player(4)}, player: 2, want: 0},
+		{name: "one teammate they asked for", team: []Player{player(1), player(2)}, player: 3, want: 1},
+		{name: "two teammates they asked for", team: []Player{player(1), player(4)}, player: 3, want: 2},
+		{name: "a teammate they asked to be kept from", team: []Player{player(2), player(5)}, player: 3, want: -1},
+		{name: "both at once", team: []Player{player(1), player(5)}, player: 3, want: 0},
+		{name: "asked by the teammate", team: []Player{player(1)}, player: 6, want: -1},
+		{name: "an empty team", player: 3, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pairFit(requests, tt.team, player(tt.player)); got != tt.want {
+				t.Errorf("got fit %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairNotes(t *testing.T) {
+	d := pairsDraft(t)
+	if err := drafts.SetPairRequests(d.pairKey(), []PairRequest{together(3, 4), apart(3, 5)}); err != nil {
+		t.Fatal(err)
+	}
+
+	notes := d.pairNotes()
+	tests := []struct {
+		player float64
+		want   []PairNote
+	}{
+		{player: 3, want: []PairNote{{Kind: togetherRequest, Other: "Player 4"}, {Kind: apartRequest, Other: "Player 5"}}},
+		{player: 4, want: []PairNote{{Kind: togetherRequest, Other: "Player 3"}}},
+		{player: 5, want: []PairNote{{Kind: apartRequest, Other: "Player 3"}}},
+		{player: 6},
+	}
+	for _, tt := range tests {
+		if got := notes.For(tt.player); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("player %v's card has %v, want %v", tt.player, got, tt.want)
+		}
+	}
+}
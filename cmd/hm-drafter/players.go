@@ -1,49 +1,35 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strconv"
 	"strings"
 )
 
-func ParsePlayers(data map[string]interface{}) Player {
+// ParsePlayers converts a raw HiveMind player object into a Player, copying the answers for the given form fields into FormFields keyed by slug
+func ParsePlayers(data map[string]interface{}, formFields []FormField) Player {
 	player := Player{
-		ID:         data["id"].(float64),
+		ID:         safeFloat(data["id"]),
 		Name:       safeString(data["name"]),
 		Scene:      safeString(data["scene"]),
 		Pronouns:   safeString(data["pronouns"]),
 		Image:      safeString(data["image"]),
-		FormFields: make(map[string]string),
+		FormFields: make(map[string]FieldValue),
 	}
 
 	// Check if "team" exists and is not nil, then convert it safely
 	if teamVal, ok := data["team"]; ok && teamVal != nil {
-		player.Team = int(teamVal.(float64)) // safely cast to int if "team" exists and is float64
+		player.Team = int(safeFloat(teamVal)) // safely cast to int if "team" exists and is a number
 	} else {
 		player.Team = 0 // Default or placeholder value if "team" is missing or nil
 	}
 
-	// Process dynamic form fields if they exist
+	// Process dynamic form fields if they exist, keeping each answer's type
 	for _, field := range formFields {
-		fieldName := field[0]
-		if value, ok := data[fieldName]; ok {
-			switch v := value.(type) {
-			case string:
-				player.FormFields[field[1]] = v
-			case []interface{}:
-				var strValues []string
-				for _, item := range v {
-					strValues = append(strValues, fmt.Sprintf("%v", item))
-				}
-				player.FormFields[field[1]] = strings.Join(strValues, ", ")
-			default:
-				player.FormFields[field[1]] = fmt.Sprintf("%v", value)
-			}
+		if value, ok := data[field.Name]; ok {
+			player.FormFields[field.Slug] = ParseFieldValue(field, value)
 		}
 	}
 
@@ -59,84 +45,71 @@ func safeString(value interface{}) string {
 	return fmt.Sprintf("%v", value)
 }
 
-// GetPlayersData retrieves all player data for the specified tournament ID, returning a slice of Players
-func GetPlayersData(tournamentID string) (players []Player) {
-	log.Println("Fetching player data...")
-
-	page := 1
-	for {
-		// Build the API URL for the current page
-		api := fmt.Sprintf(apiTemplate, "player", fmt.Sprintf("&tournament_id=%v", tournamentID), fmt.Sprintf("&page=%d", page))
-		client, req := createRequest("GET", api, nil)
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer resp.Body.Close()
-
-		// Parse the response into a PlayersApiResponse
-		var playerApiResponse PlayersApiResponse
-		err = json.NewDecoder(resp.Body).Decode(&playerApiResponse)
-		if err != nil {
-			log.Fatal(err)
-		}
-
-		// If no results are returned, exit the loop
-		if len(playerApiResponse.Results) == 0 {
-			log.Println("No more pages.")
-			break
-		}
-
-		// Parse each player result and add it to the players slice
-		for _, playerData := range playerApiResponse.Results {
-			parsedPlayer := ParsePlayers(playerData)
-			players = append(players, parsedPlayer)
-		}
+// Helper function to safely convert a JSON number to a float64, returning 0 for anything else
+func safeFloat(value interface{}) float64 {
+	if f, ok := value.(float64); ok {
+		return f
+	}
+	return 0
+}
 
-		// Increment the page for the next iteration
-		page++
+// parseID converts an ID posted from a form or URL into an int. IDs rendered from float64 fields can come back in exponent form (e.g. "1.234567e+06"), so they're parsed as floats first.
+func parseID(id string) (int, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(id), 64)
+	if err != nil {
+		return 0, err
 	}
+	return int(f), nil
+}
 
-	log.Printf("API data fetched.\nPLAYERS:\n%v", players)
-	return players
+// formatID turns a float64 player ID back into the plain integer string HiveMind expects
+func formatID(id float64) string {
+	return strconv.FormatFloat(id, 'f', -1, 64)
 }
 
+// GetPlayersData retrieves all player data for the specified tournament ID, returning a slice of Players
+func GetPlayersData(ctx context.Context, tournamentID string, formFields []FormField) (players []Player, err error) {
+	log.Println("Fetching player data...")
 
-func AssignPlayerToTeam(playerID string, teamID string, tournamentID string) {
-	// Convert team IDs to int
-	teamIDInt, err := strconv.Atoi(teamID)
+	id, err := parseID(tournamentID)
 	if err != nil {
-		log.Print("Unable to convert team ID string to int in AssignCaptainToTeam() func.")
+		return nil, fmt.Errorf("invalid tournament ID %q: %w", tournamentID, err)
 	}
 
-	// Use a map to specify only the field to update
-	updateData := map[string]interface{}{
-		"team": teamIDInt,
+	results, err := hm.Players(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching players: %w", err)
 	}
 
-	// Convert the team struct to JSON
-	playerJSON, err := json.Marshal(updateData)
-	if err != nil {
-		log.Fatalf("error marshalling player data: %v", err)
+	// Parse each player result and add it to the players slice
+	for _, playerData := range results {
+		players = append(players, ParsePlayers(playerData, formFields))
 	}
 
-	api := fmt.Sprintf("https://kqhivemind.com/api/tournament/player/%v/?tournament_id=%v&format=json", playerID, tournamentID)
+	log.Printf("API data fetched.\nPLAYERS:\n%v", players)
+	return players, nil
+}
 
-	client, req := createRequest("PATCH", api, bytes.NewBuffer(playerJSON))
-	req.Header.Set("Content-Type", "application/json")
 
-	// Make the POST request
-	resp, err := client.Do(req)
+// AssignPlayerToTeam moves a player onto a team in HiveMind. An empty or "0" team ID clears the player's team.
+func AssignPlayerToTeam(ctx context.Context, playerID string, teamID string, tournamentID string) error {
+	playerIDInt, err := parseID(playerID)
 	if err != nil {
-		log.Fatalf("error making PATCH request: %v", err)
+		return fmt.Errorf("invalid player ID %q: %w", playerID, err)
 	}
-	defer resp.Body.Close()
 
-	// Check for success (200 OK or 204 No Content)
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		log.Fatalf("Failed to modify player's team. Status: %v, Response: %s", resp.Status, string(body))
-	} else {
-		log.Printf("Successfully assigned player %v to team %v", playerID, teamID)
+	teamIDInt := 0
+	if teamID != "" {
+		teamIDInt, err = parseID(teamID)
+		if err != nil {
+			return fmt.Errorf("invalid team ID %q: %w", teamID, err)
+		}
 	}
-}
\ No newline at end of file
+
+	if err := hm.SetPlayerTeam(ctx, playerIDInt, teamIDInt); err != nil {
+		return fmt.Errorf("failed to modify player's team: %w", err)
+	}
+
+	log.Printf("Successfully assigned player %v to team %v (tournament %v)", playerID, teamID, tournamentID)
+	return nil
+}
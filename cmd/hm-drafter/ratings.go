@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ratingStart is where every player's rating begins, and the rating that lines up with ratingStartSkill
+	ratingStart = 1500.0
+
+	// ratingStartSkill is the self-reported skill a new player's rating counts as, the middle of the 1 to 5 scale
+	ratingStartSkill = 3.0
+
+	// ratingPerSkill is how many rating points make one step of self-reported skill
+	ratingPerSkill = 200.0
+
+	// ratingK is how far one match can move a player's rating
+	ratingK = 32.0
+
+	// ratingMinMatches is how many matches a player needs before their rating is used instead of their self-reported skill
+	ratingMinMatches = 3
+
+	// ratingHistoryTournaments is how many of the scene's most recent tournaments a fresh set of ratings is built from
+	ratingHistoryTournaments = 40
+
+	// ratingRefreshTimeout is how long a refresh running in the background may keep asking HiveMind
+	ratingRefreshTimeout = 5 * time.Minute
+)
+
+// Ratings are Elo-style ratings for a scene's players, worked out from the match results of its past tournaments. Players are matched by name since HiveMind gives them a new ID for every tournament.
+type Ratings struct {
+	Scene       string
+	Since       string                  // Date of the oldest tournament the ratings look at, every unrated one from then on is fetched on a refresh
+	Tournaments []RatedTournament       // Oldest first
+	Players     map[string]PlayerRating // Keyed by nameKey
+	Updated     time.Time
+}
+
+// RatedTournament is a tournament whose matches have gone into the ratings
+type RatedTournament struct {
+	ID   int
+	Name string
+	Date string
+}
+
+// PlayerRating is one player's rating and how many matches it's based on
+type PlayerRating struct {
+	Name    string
+	Rating  float64
+	Matches int
+}
+
+// Provisional reports whether the rating is based on too few matches to trust over self-reported skill
+func (p PlayerRating) Provisional() bool {
+	return p.Matches < ratingMinMatches
+}
+
+// Skill converts the rating to the self-reported skill scale, so a 1700 is about a skill 4
+func (p PlayerRating) Skill() float64 {
+	return ratingStartSkill + (p.Rating-ratingStart)/ratingPerSkill
+}
+
+func (p PlayerRating) String() string {
+	matches := "matches"
+	if p.Matches == 1 {
+		matches = "match"
+	}
+	if p.Provisional() {
+		return fmt.Sprintf("%.0f (%d %v, provisional)", p.Rating, p.Matches, matches)
+	}
+	return fmt.Sprintf("%.0f (%d %v)", p.Rating, p.Matches, matches)
+}
+
+// Lookup finds a player's rating by name
+func (r *Ratings) Lookup(name string) (PlayerRating, bool) {
+	if r == nil {
+		return PlayerRating{}, false
+	}
+	rating, ok := r.Players[nameKey(name)]
+	return rating, ok
+}
+
+// Skill is the player's skill for ranking and balancing: their rating once they've played enough matches, otherwise what they reported
+func (r *Ratings) Skill(player Player) float64 {
+	if rating, ok := r.Lookup(player.Name); ok && !rating.Provisional() {
+		return rating.Skill()
+	}
+	return playerSkill(player)
+}
+
+// LastTournament names the newest tournament in the ratings, or "" if there are none
+func (r *Ratings) LastTournament() string {
+	if r == nil || len(r.Tournaments) == 0 {
+		return ""
+	}
+	return r.Tournaments[len(r.Tournaments)-1].Name
+}
+
+// rated reports whether a tournament's matches are already in the ratings
+func (r *Ratings) rated(tournamentID int) bool {
+	for _, tournament := range r.Tournaments {
+		if tournament.ID == tournamentID {
+			return true
+		}
+	}
+	return false
+}
+
+// since is the date refreshes look back to. Ratings saved before Since existed look back to their oldest tournament.
+func (r *Ratings) since() string {
+	if r.Since == "" && len(r.Tournaments) > 0 {
+		return r.Tournaments[0].Date
+	}
+	return r.Since
+}
+
+// ratingBook caches each scene's ratings in front of the store, and tracks the refreshes running in the background
+type ratingBook struct {
+	mu         sync.Mutex
+	byScene    map[string]*Ratings
+	refreshing map[string]bool
+	failed     map[string]error // Why a scene's last refresh failed, cleared by the next one
+}
+
+// Ratings returns a scene's saved ratings, or nil if they've never been loaded from HiveMind
+func (r *DraftRegistry) Ratings(scene string) *Ratings {
+	r.ratings.mu.Lock()
+	defer r.ratings.mu.Unlock()
+
+	if r.ratings.byScene == nil {
+		r.ratings.byScene = make(map[string]*Ratings)
+	}
+	if ratings, ok := r.ratings.byScene[scene]; ok {
+		return ratings
+	}
+
+	ratings, err := r.store.LoadRatings(scene)
+	if err != nil {
+		log.Printf("Failed to load ratings for %v: %v", scene, err)
+		return nil
+	}
+	r.ratings.byScene[scene] = ratings
+	return ratings
+}
+
+// SetRatings saves a scene's ratings
+func (r *DraftRegistry) SetRatings(ratings *Ratings) error {
+	if err := r.store.SaveRatings(ratings); err != nil {
+		return err
+	}
+
+	r.ratings.mu.Lock()
+	defer r.ratings.mu.Unlock()
+	if r.ratings.byScene == nil {
+		r.ratings.byScene = make(map[string]*Ratings)
+	}
+	r.ratings.byScene[ratings.Scene] = ratings
+	return nil
+}
+
+// RefreshRatings brings a scene's ratings up to date with HiveMind. A first load looks at the most recent tournaments, up to the cap. After that every tournament since the oldest one looked at is fetched unless it's already rated, so tournaments skipped for having no results yet are picked up once they do. New tournaments are played in oldest first so each match moves the ratings in the order it was played.
+func RefreshRatings(ctx context.Context, scene string) (*Ratings, error) {
+	ratings := &Ratings{Scene: scene, Players: make(map[string]PlayerRating)}
+	if saved := drafts.Ratings(scene); saved != nil {
+		ratings.Since = saved.since()
+		ratings.Tournaments = append(ratings.Tournaments, saved.Tournaments...)
+		for key, rating := range saved.Players {
+			ratings.Players[key] = rating
+		}
+	}
+
+	// Walk the scene's past tournaments newest first
+	fresh := ratings.Since == ""
+	var unrated []RatedTournament
+	looked, done := 0, false
+	for page, pages := 1, 1; page <= pages && !done; page++ {
+		results, err := SearchTournaments(ctx, TournamentFilter{Scene: scene, When: "past", Page: page})
+		if err != nil {
+			return nil, err
+		}
+		pages = results.Pages
+
+		for _, tournament := range results.Tournaments {
+			date := tournamentDate(tournament)
+			if (fresh && looked == ratingHistoryTournaments) || (!fresh && date < ratings.Since) {
+				done = true
+				break
+			}
+			looked++
+			if fresh {
+				ratings.Since = date
+			}
+			if !ratings.rated(tournament.ID) {
+				unrated = append(unrated, RatedTournament{ID: tournament.ID, Name: tournament.Name, Date: date})
+			}
+		}
+	}
+
+	for i := len(unrated) - 1; i >= 0; i-- {
+		played, err := ratings.addTournament(ctx, unrated[i])
+		if err != nil {
+			return nil, fmt.Errorf("fetching the results of %v: %w", unrated[i].Name, err)
+		}
+		// Tournaments without results yet are left out so a later refresh picks them up
+		if played > 0 {
+			ratings.Tournaments = append(ratings.Tournaments, unrated[i])
+		}
+	}
+
+	sort.SliceStable(ratings.Tournaments, func(i, j int) bool { return ratings.Tournaments[i].Date < ratings.Tournaments[j].Date })
+	ratings.Updated = time.Now()
+
+	if err := drafts.SetRatings(ratings); err != nil {
+		return nil, fmt.Errorf("saving ratings: %w", err)
+	}
+	log.Printf("Refreshed ratings for %v: %v unrated tournaments checked, %v players rated", scene, len(unrated), len(ratings.Players))
+	return ratings, nil
+}
+
+// RefreshRatingsInBackground starts refreshing a scene's ratings without holding up the request or any draft's lock, since a first load can take longer than Heroku lets a request run. Drafts in the scene reload when it's done. It returns false if the scene is already refreshing.
+func (r *DraftRegistry) RefreshRatingsInBackground(scene string) bool {
+	r.ratings.mu.Lock()
+	defer r.ratings.mu.Unlock()
+
+	if r.ratings.refreshing[scene] {
+		return false
+	}
+	if r.ratings.refreshing == nil {
+		r.ratings.refreshing = make(map[string]bool)
+		r.ratings.failed = make(map[string]error)
+	}
+	r.ratings.refreshing[scene] = true
+	delete(r.ratings.failed, scene)
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ratingRefreshTimeout)
+		defer cancel()
+
+		_, err := RefreshRatings(ctx, scene)
+		if err != nil {
+			log.Printf("Failed to refresh ratings for %v: %v", scene, err)
+		}
+
+		r.ratings.mu.Lock()
+		delete(r.ratings.refreshing, scene)
+		if err != nil {
+			r.ratings.failed[scene] = err
+		}
+		r.ratings.mu.Unlock()
+
+		for _, d := range r.List() {
+			d.mu.Lock()
+			if d.sceneName() == scene {
+				d.publish("reload", nil)
+			}
+			d.mu.Unlock()
+		}
+	}()
+	return true
+}
+
+// RatingsRefresh reports whether a scene's ratings are refreshing, and why the last refresh failed if it did
+func (r *DraftRegistry) RatingsRefresh(scene string) (refreshing bool, failed error) {
+	r.ratings.mu.Lock()
+	defer r.ratings.mu.Unlock()
+	return r.ratings.refreshing[scene], r.ratings.failed[scene]
+}
+
+// addTournament plays a tournament's finished matches into the ratings, returning how many there were
+func (r *Ratings) addTournament(ctx context.Context, tournament RatedTournament) (played int, err error) {
+	matches, err := hm.Matches(ctx, tournament.ID)
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].ID < matches[j].ID })
+
+	var rosters map[int][]string
+	for _, match := range matches {
+		if !match.IsComplete || match.BlueScore+match.GoldScore == 0 {
+			continue
+		}
+		if rosters == nil {
+			if rosters, err = tournamentRosters(ctx, tournament.ID); err != nil {
+				return 0, err
+			}
+		}
+
+		blue, gold := rosters[match.BlueTeam], rosters[match.GoldTeam]
+		if len(blue) == 0 || len(gold) == 0 {
+			continue
+		}
+
+		// The expected share of games comes from the teams' average ratings, and the result is the share blue actually won
+		expected := 1 / (1 + math.Pow(10, (r.teamRating(gold)-r.teamRating(blue))/400))
+		actual := float64(match.BlueScore) / float64(match.BlueScore+match.GoldScore)
+		change := ratingK * (actual - expected)
+
+		r.adjust(blue, change)
+		r.adjust(gold, -change)
+		played++
+	}
+	return played, nil
+}
+
+// teamRating averages the ratings of a team's players, counting new players at the starting rating
+func (r *Ratings) teamRating(team []string) float64 {
+	total := 0.0
+	for _, name := range team {
+		if rating, ok := r.Players[nameKey(name)]; ok {
+			total += rating.Rating
+		} else {
+			total += ratingStart
+		}
+	}
+	return total / float64(len(team))
+}
+
+// adjust moves every player on a team by change and counts the match
+func (r *Ratings) adjust(team []string, change float64) {
+	for _, name := range team {
+		key := nameKey(name)
+		rating, ok := r.Players[key]
+		if !ok {
+			rating.Rating = ratingStart
+		}
+		rating.Name = name
+		rating.Rating += change
+		rating.Matches++
+		r.Players[key] = rating
+	}
+}
+
+// sceneName is the scene the draft's players come from
+func (d *Draft) sceneName() string {
+	if d.Scene == "" {
+		return defaultScene
+	}
+	return d.Scene
+}
+
+// sceneRatings returns the saved ratings for the draft's scene, without asking HiveMind
+func (d *Draft) sceneRatings() *Ratings {
+	return drafts.Ratings(d.sceneName())
+}
+
+// SetUseRatings switches ranking, balancing and auto-picks between HiveMind ratings and self-reported skill
+func (d *Draft) SetUseRatings(on bool) error {
+	if on && d.sceneRatings() == nil {
+		return fmt.Errorf("load the scene's ratings from HiveMind first")
+	}
+	d.UseRatings = on
+	log.Printf("Draft %v: use ratings %v", d.ID, on)
+	d.publish("reload", nil)
+	return nil
+}
+
+// ratings are what auto-picks, balancing and the best available ranking should go by, or nil to go by self-reported skill
+func (d *Draft) ratings() *Ratings {
+	if !d.UseRatings {
+		return nil
+	}
+	return d.sceneRatings()
+}
+
+// RatingNotes looks up the rating on each player's card
+type RatingNotes map[float64]string
+
+// For returns the rating for a player's card, or "" if they haven't played any rated matches
+func (n RatingNotes) For(playerID float64) string {
+	return n[playerID]
+}
+
+// ratingNotes lists the rating of every player in the draft who has one
+func (d *Draft) ratingNotes() RatingNotes {
+	ratings := d.sceneRatings()
+	if ratings == nil {
+		return nil
+	}
+
+	notes := make(RatingNotes)
+	for _, player := range d.Players {
+		if rating, ok := ratings.Lookup(player.Name); ok {
+			notes[player.ID] = rating.String()
+		}
+	}
+	return notes
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+func TestPlayerRating(t *testing.T) {
+	tests := []struct {
+		rating      PlayerRating
+		skill       float64
+		provisional bool
+		text        string
+	}{
+		{rating: PlayerRating{Rating: 1500, Matches: 3}, skill: 3, text: "1500 (3 matches)"},
+		{rating: PlayerRating{Rating: 1700, Matches: 10}, skill: 4, text: "1700 (10 matches)"},
+		{rating: PlayerRating{Rating: 1250, Matches: 5}, skill: 1.75, text: "1250 (5 matches)"},
+		{rating: PlayerRating{Rating: 1516.4, Matches: 1}, skill: 3.082, provisional: true, text: "1516 (1 match, provisional)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rating.Skill(); math.Abs(got-tt.skill) > 1e-9 {
+			t.Errorf("%v: skill %v, want %v", tt.rating.Rating, got, tt.skill)
+		}
+		if got := tt.rating.Provisional(); got != tt.provisional {
+			t.Errorf("%v: provisional %v, want %v", tt.rating.Rating, got, tt.provisional)
+		}
+		if got := tt.rating.String(); got != tt.text {
+			t.Errorf("%v: got %q, want %q", tt.rating.Rating, got, tt.text)
+		}
+	}
+}
+
+func TestRatingsSkill(t *testing.T) {
+	ratings := &Ratings{Players: map[string]PlayerRating{
+		nameKey("Rated"):       {Name: "Rated", Rating: 1900, Matches: 8},
+		nameKey("Provisional"): {Name: "Provisional", Rating: 1900, Matches: 2},
+	}}
+	skilled := func(name string) Player {
+		return Player{Name: name, FormFields: map[string]FieldValue{"skill": NumberValue(2)}}
+	}
+
+	tests := []struct {
+		ratings *Ratings
+		player  string
+		want    float64
+	}{
+		{ratings: ratings, player: "Rated", want: 5},
+		{ratings: ratings, player: "Provisional", want: 2},
+		{ratings: ratings, player: "Unrated", want: 2},
+		{ratings: nil, player: "Rated", want: 2}, // Not using ratings
+	}
+	for _, tt := range tests {
+		if got := tt.ratings.Skill(skilled(tt.player)); got != tt.want {
+			t.Errorf("%v: got skill %v, want %v", tt.player, got, tt.want)
+		}
+	}
+}
+
+// matchFixtures is one past tournament where Blue and Gold, two players each, play a single match
+func matchFixtures(blueScore, goldScore int) *hivemindtest.Fixtures {
+	return &hivemindtest.Fixtures{
+		Tournaments: []map[string]interface{}{{"id": 1, "name": "Test Night", "date": "2026-01-01", "scene_name": "test"}},
+		Teams:       []hivemindtest.Team{{ID: 10, Name: "Blue", Tournament: 1}, {ID: 20, Name: "Gold", Tournament: 1}},
+		Players: []map[string]interface{}{
+			{"id": 100, "name": "Blue A", "team": 10, "tournament": 1},
+			{"id": 101, "name": "Blue B", "team": 10, "tournament": 1},
+			{"id": 200, "name": "Gold A", "team": 20, "tournament": 1},
+			{"id": 201, "name": "Gold B", "team": 20, "tournament": 1},
+		},
+		Matches: []hivemindtest.Match{{ID: 1, Tournament: 1, BlueTeam: 10, GoldTeam: 20, BlueScore: blueScore, GoldScore: goldScore, IsComplete: true}},
+	}
+}
+
+func TestAddTournament(t *testing.T) {
+	tests := []struct {
+		name       string
+		blue, gold float64 // Ratings going in
+		blueScore  int
+		goldScore  int
+		wantChange float64 // How far each blue player moves
+	}{
+		{name: "even teams, blue sweeps", blue: 1500, gold: 1500, blueScore: 2, goldScore: 0, wantChange: 16},
+		{name: "even teams, split", blue: 1500, gold: 1500, blueScore: 1, goldScore: 1, wantChange: 0},
+		{name: "even teams, blue wins 2-1", blue: 1500, gold: 1500, blueScore: 2, goldScore: 1, wantChange: 32 * (2.0/3 - 0.5)},
+		{name: "favorite wins as expected", blue: 1900, gold: 1500, blueScore: 2, goldScore: 0, wantChange: 32 * (1 - 10.0/11)},
+		{name: "favorite is upset", blue: 1900, gold: 1500, blueScore: 0, goldScore: 2, wantChange: -32 * 10.0 / 11},
+		{name: "no games played", blue: 1500, gold: 1500, blueScore: 0, goldScore: 0, wantChange: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveFakeHiveMind(t, matchFixtures(tt.blueScore, tt.goldScore))
+			ratings := &Ratings{Players: map[string]PlayerRating{}}
+			for _, name := range []string{"Blue A", "Blue B"} {
+				ratings.Players[nameKey(name)] = PlayerRating{Name: name, Rating: tt.blue}
+			}
+			for _, name := range []string{"Gold A", "Gold B"} {
+				ratings.Players[nameKey(name)] = PlayerRating{Name: name, Rating: tt.gold}
+			}
+
+			played, err := ratings.addTournament(context.Background(), RatedTournament{ID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantPlayed := 1
+			if tt.blueScore+tt.goldScore == 0 {
+				wantPlayed = 0
+			}
+			if played != wantPlayed {
+				t.Errorf("played %d matches, want %d", played, wantPlayed)
+			}
+
+			// Both teams move by the same amount in opposite directions
+			for name, start := range map[string]float64{"Blue A": tt.blue, "Gold B": tt.gold} {
+				want := start + tt.wantChange
+				if name == "Gold B" {
+					want = start - tt.wantChange
+				}
+				got, _ := ratings.Lookup(name)
+				if math.Abs(got.Rating-want) > 1e-9 {
+					t.Errorf("%v is rated %v, want %v", name, got.Rating, want)
+				}
+				if got.Matches != wantPlayed {
+					t.Errorf("%v has %d matches, want %d", name, got.Matches, wantPlayed)
+				}
+			}
+		})
+	}
+}
+
+func TestRefreshRatings(t *testing.T) {
+	ctx := context.Background()
+	startFakeHiveMind(t)
+
+	// A first load rates every past tournament in the scene, and the ratings only move points between players
+	ratings, err := RefreshRatings(ctx, "kqpdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ratings.Tournaments) != 2 || ratings.LastTournament() != "PDX Mixer - February" {
+		t.Fatalf("rated %+v, want January and February", ratings.Tournaments)
+	}
+	total := 0.0
+	for _, rating := range ratings.Players {
+		total += rating.Rating - ratingStart
+	}
+	if math.Abs(total) > 1e-6 {
+		t.Errorf("ratings are %v points off the starting total", total)
+	}
+
+	// Refreshing with only January saved fetches February again, and only February
+	january := &Ratings{Scene: "kqpdx", Tournaments: ratings.Tournaments[:1], Players: map[string]PlayerRating{}}
+	if _, err := january.addTournament(ctx, january.Tournaments[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := drafts.SetRatings(january); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := RefreshRatings(ctx, "kqpdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed.Tournaments) != 2 {
+		t.Fatalf("refreshed ratings cover %+v, want January and February", refreshed.Tournaments)
+	}
+	for key, rating := range ratings.Players {
+		if got := refreshed.Players[key]; math.Abs(got.Rating-rating.Rating) > 1e-9 || got.Matches != rating.Matches {
+			t.Errorf("%v: refreshed to %v, want %v as from a first load", rating.Name, got, rating)
+		}
+	}
+
+	// Nothing new since February
+	again, err := RefreshRatings(ctx, "kqpdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Tournaments) != 2 {
+		t.Errorf("a refresh with nothing new rated %+v", again.Tournaments)
+	}
+
+	// January had no results when February was rated, so it's still fetched even though it's older than February
+	february := &Ratings{Scene: "kqpdx", Since: ratings.Tournaments[0].Date, Tournaments: ratings.Tournaments[1:], Players: map[string]PlayerRating{}}
+	if err := drafts.SetRatings(february); err != nil {
+		t.Fatal(err)
+	}
+	caughtUp, err := RefreshRatings(ctx, "kqpdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caughtUp.Tournaments) != 2 || caughtUp.Tournaments[0].Name != "PDX Mixer - January" {
+		t.Errorf("refreshed ratings cover %+v, want January picked up", caughtUp.Tournaments)
+	}
+}
+
+func TestRefreshRatingsInBackground(t *testing.T) {
+	startFakeHiveMind(t)
+	d := drafts.Create()
+	d.Scene = "kqpdx"
+	_, events, _ := d.events.Subscribe(d.events.LastID())
+	defer d.events.Unsubscribe(events)
+
+	if !drafts.RefreshRatingsInBackground("kqpdx") {
+		t.Fatal("the refresh didn't start")
+	}
+
+	// The scene's drafts reload once the ratings are in
+	select {
+	case event := <-events:
+		if event.Type != "reload" {
+			t.Errorf("got a %v event, want reload", event.Type)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the draft never heard the refresh finished")
+	}
+
+	refreshing, failed := drafts.RatingsRefresh("kqpdx")
+	if refreshing || failed != nil {
+		t.Errorf("after the refresh: refreshing %v, failed %v", refreshing, failed)
+	}
+	if ratings := drafts.Ratings("kqpdx"); ratings == nil || len(ratings.Tournaments) != 2 {
+		t.Errorf("the refresh saved %+v, want January and February", ratings)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/imandradesign/hm-drafter/hivemind"
+)
+
+// registrationTimeLayout is how registration close times are written in -registration-closes
+const registrationTimeLayout = "2006-01-02 15:04"
+
+var (
+	// registrationInputs are the form inputs that aren't tournament form fields
+	registrationInputs = []string{"tournamentID", "name", "pronouns"}
+
+	// registrationCloses maps a tournament ID to when its registration closes, set with -registration-closes
+	registrationCloses = map[string]time.Time{}
+
+	// registrationMu makes the duplicate check and the write to HiveMind one step, so a double-clicked submit can't register someone twice
+	registrationMu sync.Mutex
+
+	errDuplicateRegistration = errors.New("already registered")
+)
+
+// Registration is one player's sign-up for a tournament
+type Registration struct {
+	TournamentID string
+	Name         string
+	Pronouns     string
+	Answers      map[string]interface{} // Form field answers keyed by slug
+}
+
+// RegistrationAnswer is one answer as shown on the confirmation page
+type RegistrationAnswer struct {
+	Label string
+	Value string
+}
+
+// AnswerList lists the answers in form order, readable as text
+func (reg Registration) AnswerList(formFields []FormField) (answers []RegistrationAnswer) {
+	for _, field := range formFields {
+		answer, ok := reg.Answers[field.Slug]
+		if !ok {
+			continue
+		}
+		answers = append(answers, RegistrationAnswer{Label: field.Label(), Value: ParseFieldValue(field, answer).String()})
+	}
+	return answers
+}
+
+// ParseRegistrationCloses reads "tournament ID=YYYY-MM-DD HH:MM" entries separated by semicolons, in the server's time zone
+func ParseRegistrationCloses(spec string) (map[string]time.Time, error) {
+	closes := make(map[string]time.Time)
+	for _, entry := range strings.Split(spec, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		id, at, found := strings.Cut(entry, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid registration close %q, expected \"<tournament ID>=YYYY-MM-DD HH:MM\"", entry)
+		}
+		closesAt, err := time.ParseInLocation(registrationTimeLayout, strings.TrimSpace(at), time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registration close time for tournament %v: %w", strings.TrimSpace(id), err)
+		}
+		closes[strings.TrimSpace(id)] = closesAt
+	}
+	return closes, nil
+}
+
+// registrationClosesAt returns when a tournament's registration closes, or the zero time if it stays open
+func registrationClosesAt(tournamentID string) time.Time {
+	return registrationCloses[tournamentID]
+}
+
+// registrationOpen reports whether a tournament is still taking registrations
+func registrationOpen(tournamentID string) bool {
+	closes := registrationClosesAt(tournamentID)
+	return closes.IsZero() || time.Now().Before(closes)
+}
+
+// ParseRegistration checks a submitted registration form against the tournament's form fields. It returns every problem it finds so the player can fix them all at once.
+func ParseRegistration(tournamentID string, form url.Values, formFields []FormField) (reg Registration, problems []string) {
+	reg = Registration{
+		TournamentID: tournamentID,
+		Name:         strings.Join(strings.Fields(form.Get("name")), " "),
+		Pronouns:     strings.TrimSpace(form.Get("pronouns")),
+		Answers:      make(map[string]interface{}),
+	}
+
+	if reg.Name == "" {
+		problems = append(problems, "Enter your name.")
+	} else if len(reg.Name) > 50 {
+		problems = append(problems, "Your name can be at most 50 characters.")
+	}
+
+	// Only answer the questions this tournament asks
+	for key := range form {
+		if _, ok := findFormField(formFields, key); !ok && !containsString(registrationInputs, key) {
+			problems = append(problems, fmt.Sprintf("This tournament doesn't ask for %v.", key))
+		}
+	}
+
+	for _, field := range formFields {
+		answer, problem := parseAnswer(field, form[field.Slug])
+		if problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		if answer != nil {
+			reg.Answers[field.Slug] = answer
+		}
+	}
+
+	return reg, problems
+}
+
+// parseAnswer checks one field's submitted values, returning the answer in the shape HiveMind stores it or a problem to show the player
+func parseAnswer(field FormField, values []string) (answer interface{}, problem string) {
+	value := ""
+	if len(values) > 0 {
+		value = strings.TrimSpace(values[0])
+	}
+
+	switch field.Type {
+	case checkboxField:
+		// Checkboxes are only sent when they're ticked
+		return value != "", ""
+
+	case multiselectField:
+		var choices []interface{}
+		for _, choice := range values {
+			if !containsString(field.Choices, choice) {
+				return nil, fmt.Sprintf("%q isn't one of the choices for %v.", choice, field.Label())
+			}
+			choices = append(choices, choice)
+		}
+		if field.Required && len(choices) == 0 {
+			return nil, fmt.Sprintf("Choose at least one for %v.", field.Label())
+		}
+		return choices, ""
+	}
+
+	if value == "" {
+		if field.Required {
+			return nil, fmt.Sprintf("Answer %v.", field.Label())
+		}
+		return nil, ""
+	}
+
+	switch field.Type {
+	case numberField:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Sprintf("%v needs a whole number.", field.Label())
+		}
+		if (field.Min != 0 || field.Max != 0) && (n < field.Min || n > field.Max) {
+			return nil, fmt.Sprintf("%v needs a number from %d to %d.", field.Label(), field.Min, field.Max)
+		}
+		return n, ""
+
+	case emailField:
+		if _, err := mail.ParseAddress(value); err != nil {
+			return nil, fmt.Sprintf("%q isn't a valid email address for %v.", value, field.Label())
+		}
+
+	case selectField:
+		if !containsString(field.Choices, value) {
+			return nil, fmt.Sprintf("%q isn't one of the choices for %v.", value, field.Label())
+		}
+	}
+	return value, ""
+}
+
+// registrationPageData returns what register.html expects for a tournament, plus any extras
+func registrationPageData(tournament *hivemind.Tournament, formFields []FormField, extra gin.H) gin.H {
+	tournamentID := strconv.Itoa(tournament.ID)
+
+	data := gin.H{
+		"branding":   BrandingFor(tournament.SceneName),
+		"tournament": tournament,
+		"formFields": formFields,
+		"open":       registrationOpen(tournamentID),
+		"closes":     registrationClosesAt(tournamentID),
+		"values":     url.Values{},
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	// Keep what they picked selected when the form comes back with problems
+	selected := make(map[string]map[string]bool)
+	for _, field := range formFields {
+		selected[field.Slug] = make(map[string]bool)
+		for _, value := range data["values"].(url.Values)[field.Slug] {
+			selected[field.Slug][value] = true
+		}
+	}
+	data["selected"] = selected
+
+	return data
+}
+
+// sameRegistrant compares names ignoring case and spacing, and the answers to any email fields ignoring case
+func sameRegistrant(reg Registration, existing map[string]interface{}, formFields []FormField) bool {
+	name := strings.Join(strings.Fields(safeString(existing["name"])), " ")
+	if strings.EqualFold(name, reg.Name) {
+		return true
+	}
+	for _, field := range formFields {
+		if field.Type != emailField {
+			continue
+		}
+		email, _ := reg.Answers[field.Slug].(string)
+		if email != "" && strings.EqualFold(strings.TrimSpace(safeString(existing[field.Name])), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterPlayer creates the player in HiveMind, unless someone with the same name or email has already registered for the tournament
+func RegisterPlayer(ctx context.Context, reg Registration, formFields []FormField) (float64, error) {
+	id, err := parseID(reg.TournamentID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tournament ID %q: %w", reg.TournamentID, err)
+	}
+
+	registrationMu.Lock()
+	defer registrationMu.Unlock()
+
+	existing, err := hm.Players(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("fetching players: %w", err)
+	}
+	for _, player := range existing {
+		if sameRegistrant(reg, player, formFields) {
+			return 0, fmt.Errorf("%v is %w for this tournament", reg.Name, errDuplicateRegistration)
+		}
+	}
+
+	player := map[string]interface{}{
+		"tournament": id,
+		"name":       reg.Name,
+		"pronouns":   reg.Pronouns,
+	}
+
+	// HiveMind keeps answers under each field's name rather than its slug
+	for _, field := range formFields {
+		if answer, ok := reg.Answers[field.Slug]; ok {
+			player[field.Name] = answer
+		}
+	}
+
+	created, err := hm.CreatePlayer(ctx, player)
+	if err != nil {
+		return 0, fmt.Errorf("registering %v: %w", reg.Name, err)
+	}
+
+	log.Printf("Registered %v for tournament %v (player ID: %v)", reg.Name, reg.TournamentID, safeString(created["id"]))
+	return safeFloat(created["id"]), nil
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+// registrationFields are the fields of the fake tournament registrationFixtures sets up
+var registrationFields = []FormField{
+	NewFormField("f_skill", "skill", "Skill level", "", nil, false),
+	NewFormField("f_roles", "roles", "Roles you play", "", nil, false),
+	NewFormField("f_sub", "sub", "Happy to sub?", "", nil, false),
+	NewFormField("f_captain", "captain", "Want to captain?", "", nil, false),
+	NewFormField("f_email", "email", "Email", "", nil, true),
+	NewFormField("f_notes", "notes", "Anything else?", "", nil, false),
+}
+
+// registrationFixtures is tournament 1 with registrationFields and Alice already registered
+func registrationFixtures() *hivemindtest.Fixtures {
+	fx := &hivemindtest.Fixtures{
+		Tournaments: []map[string]interface{}{{"id": 1, "name": "PDX Mixer", "date": "2026-11-07", "scene_name": "kqpdx"}},
+		Players: []map[string]interface{}{
+			{"id": 5, "tournament": 1, "name": " Alice  Smith ", "f_email": " ALICE@example.com", "f_skill": 4},
+		},
+	}
+	for i, field := range registrationFields {
+		fx.FormFields = append(fx.FormFields, hivemindtest.FormField{
+			ID: i + 1, Tournament: 1, FieldName: field.Name, FieldSlug: field.Slug, FieldDescription: field.Description,
+		})
+	}
+	return fx
+}
+
+func TestParseAnswer(t *testing.T) {
+	skill, _ := findFormField(registrationFields, "skill")
+	roles, _ := findFormField(registrationFields, "roles")
+	sub, _ := findFormField(registrationFields, "sub")
+	captain, _ := findFormField(registrationFields, "captain")
+	email, _ := findFormField(registrationFields, "email")
+	notes, _ := findFormField(registrationFields, "notes")
+	anyNumber := NewFormField("f_games", "games", "Games played", "number", nil, false)
+
+	tests := []struct {
+		name        string
+		field       FormField
+		values      []string
+		want        interface{}
+		wantProblem string
+	}{
+		{name: "number", field: skill, values: []string{" 3 "}, want: 3},
+		{name: "number at the top of the range", field: skill, values: []string{"5"}, want: 5},
+		{name: "number out of range", field: skill, values: []string{"6"}, wantProblem: "Skill level needs a number from 1 to 5."},
+		{name: "number that isn't whole", field: skill, values: []string{"2.5"}, wantProblem: "Skill level needs a whole number."},
+		{name: "number without a range", field: anyNumber, values: []string{"-40"}, want: -40},
+		{name: "required number left blank", field: skill, values: []string{" "}, wantProblem: "Answer Skill level."},
+		{name: "multiselect", field: roles, values: []string{"Queen", "Objective Runner"}, want: []interface{}{"Queen", "Objective Runner"}},
+		{name: "multiselect with a made up choice", field: roles, values: []string{"Queen", "Snail"}, wantProblem: `"Snail" isn't one of the choices for Roles you play.`},
+		{name: "required multiselect left blank", field: roles, wantProblem: "Choose at least one for Roles you play."},
+		{name: "ticked checkbox", field: sub, values: []string{"on"}, want: true},
+		{name: "unticked checkbox", field: sub, want: false},
+		{name: "select", field: captain, values: []string{"Yes"}, want: "Yes"},
+		{name: "select with a made up choice", field: captain, values: []string{"Maybe"}, wantProblem: `"Maybe" isn't one of the choices for Want to captain?.`},
+		{name: "optional select left blank", field: captain, values: []string{""}},
+		{name: "email", field: email, values: []string{" player@example.com "}, want: "player@example.com"},
+		{name: "email with a name", field: email, values: []string{"Player <player@example.com>"}, want: "Player <player@example.com>"},
+		{name: "invalid email", field: email, values: []string{"player@"}, wantProblem: `"player@" isn't a valid email address for Email.`},
+		{name: "email without an at", field: email, values: []string{"player.example.com"}, wantProblem: `"player.example.com" isn't a valid email address for Email.`},
+		{name: "required email left blank", field: email, wantProblem: "Answer Email."},
+		{name: "text", field: notes, values: []string{" bringing snacks ", "ignored"}, want: "bringing snacks"},
+		{name: "optional text left blank", field: notes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problem := parseAnswer(tt.field, tt.values)
+			if problem != tt.wantProblem {
+				t.Errorf("got problem %q, want %q", problem, tt.wantProblem)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got answer %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRegistration(t *testing.T) {
+	valid := func(changes url.Values) url.Values {
+		form := url.Values{
+			"tournamentID": {"1"},
+			"name":         {"  Bob   Jones "},
+			"pronouns":     {" he/him "},
+			"skill":        {"3"},
+			"roles":        {"Queen"},
+			"email":        {"bob@example.com"},
+		}
+		for key, values := range changes {
+			if values == nil {
+				form.Del(key)
+			} else {
+				form[key] = values
+			}
+		}
+		return form
+	}
+
+	tests := []struct {
+		name         string
+		form         url.Values
+		wantAnswers  map[string]interface{}
+		wantProblems []string
+	}{
+		{
+			name:        "valid",
+			form:        valid(nil),
+			wantAnswers: map[string]interface{}{"skill": 3, "roles": []interface{}{"Queen"}, "sub": false, "email": "bob@example.com"},
+		},
+		{
+			name:         "no name",
+			form:         valid(url.Values{"name": {"   "}}),
+			wantProblems: []string{"Enter your name."},
+		},
+		{
+			name:         "long name",
+			form:         valid(url.Values{"name": {strings.Repeat("Bob ", 13)}}),
+			wantProblems: []string{"Your name can be at most 50 characters."},
+		},
+		{
+			name:         "a question the tournament doesn't ask",
+			form:         valid(url.Values{"favorite_map": {"Helix"}}),
+			wantProblems: []string{"This tournament doesn't ask for favorite_map."},
+		},
+		{
+			name: "every problem at once",
+			form: valid(url.Values{"name": nil, "skill": {"9"}, "roles": nil, "email": {"bob"}}),
+			wantProblems: []string{
+				"Enter your name.",
+				"Skill level needs a number from 1 to 5.",
+				"Choose at least one for Roles you play.",
+				`"bob" isn't a valid email address for Email.`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, problems := ParseRegistration("1", tt.form, registrationFields)
+			if !reflect.DeepEqual(problems, tt.wantProblems) {
+				t.Errorf("got problems %q, want %q", problems, tt.wantProblems)
+			}
+			if tt.wantProblems != nil {
+				return
+			}
+			if reg.TournamentID != "1" || reg.Name != "Bob Jones" || reg.Pronouns != "he/him" {
+				t.Errorf("got registration %+v", reg)
+			}
+			if !reflect.DeepEqual(reg.Answers, tt.wantAnswers) {
+				t.Errorf("got answers %#v, want %#v", reg.Answers, tt.wantAnswers)
+			}
+		})
+	}
+}
+
+func TestParseRegistrationCloses(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]time.Time
+		wantErr bool
+	}{
+		{spec: "", want: map[string]time.Time{}},
+		{
+			spec: " 104 = 2026-11-07 18:30 ; 105=2026-12-01 09:00;",
+			want: map[string]time.Time{
+				"104": time.Date(2026, 11, 7, 18, 30, 0, 0, time.Local),
+				"105": time.Date(2026, 12, 1, 9, 0, 0, 0, time.Local),
+			},
+		},
+		{spec: "104", wantErr: true},
+		{spec: "104=November 7th", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRegistrationCloses(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRegistrationCloses(%q) got error %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRegistrationCloses(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestSameRegistrant(t *testing.T) {
+	existing := map[string]interface{}{"name": " Alice  Smith ", "f_email": " ALICE@example.com"}
+
+	tests := []struct {
+		name    string
+		regName string
+		email   string
+		want    bool
+	}{
+		{name: "same name", regName: "Alice Smith", want: true},
+		{name: "same name in another case", regName: "alice smith", want: true},
+		{name: "same email in another case", regName: "Al", email: "alice@EXAMPLE.com", want: true},
+		{name: "someone else", regName: "Alice Jones", email: "ajones@example.com"},
+		{name: "someone else without an email", regName: "Alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := Registration{Name: tt.regName, Answers: map[string]interface{}{}}
+			if tt.email != "" {
+				reg.Answers["email"] = tt.email
+			}
+			if got := sameRegistrant(reg, existing, registrationFields); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterPlayer(t *testing.T) {
+	tests := []struct {
+		name          string
+		reg           Registration
+		wantErr       bool
+		wantDuplicate bool
+		wantRegistry  int // Players registered for the tournament afterwards
+	}{
+		{
+			name:         "new player",
+			reg:          Registration{TournamentID: "1", Name: "Bob Jones", Pronouns: "he/him", Answers: map[string]interface{}{"skill": 3, "email": "bob@example.com"}},
+			wantRegistry: 2,
+		},
+		{
+			name:          "same name as someone registered",
+			reg:           Registration{TournamentID: "1", Name: "alice smith", Answers: map[string]interface{}{"email": "a2@example.com"}},
+			wantErr:       true,
+			wantDuplicate: true,
+			wantRegistry:  1,
+		},
+		{
+			name:          "same email as someone registered",
+			reg:           Registration{TournamentID: "1", Name: "Al", Answers: map[string]interface{}{"email": "alice@example.com"}},
+			wantErr:       true,
+			wantDuplicate: true,
+			wantRegistry:  1,
+		},
+		{
+			name:         "invalid tournament ID",
+			reg:          Registration{TournamentID: "mixer", Name: "Bob Jones"},
+			wantErr:      true,
+			wantRegistry: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveFakeHiveMind(t, registrationFixtures())
+			ctx := context.Background()
+
+			id, err := RegisterPlayer(ctx, tt.reg, registrationFields)
+			if (err != nil) != tt.wantErr || errors.Is(err, errDuplicateRegistration) != tt.wantDuplicate {
+				t.Fatalf("got error %v, want error %v and duplicate %v", err, tt.wantErr, tt.wantDuplicate)
+			}
+
+			players, err := hm.Players(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(players) != tt.wantRegistry {
+				t.Fatalf("the tournament has %v players, want %v", len(players), tt.wantRegistry)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// Answers are stored under each field's HiveMind name
+			created := players[len(players)-1]
+			if safeFloat(created["id"]) != id || created["name"] != "Bob Jones" || created["pronouns"] != "he/him" || safeFloat(created["f_skill"]) != 3 || created["f_email"] != "bob@example.com" {
+				t.Errorf("registered %v, want Bob with player ID %v and his answers under the field names", created, id)
+			}
+		})
+	}
+}
+
+func TestRegisterRoute(t *testing.T) {
+	serveFakeHiveMind(t, registrationFixtures())
+	oldCloses := registrationCloses
+	t.Cleanup(func() { registrationCloses = oldCloses })
+	registrationCloses = map[string]time.Time{}
+
+	client := &testClient{t: t, router: setupRouter(), cookies: map[string]*http.Cookie{}}
+	form := url.Values{
+		"tournamentID": {"1"},
+		"name":         {"Bob Jones"},
+		"skill":        {"3"},
+		"roles":        {"Queen", "Speed Warrior"},
+		"email":        {"bob@example.com"},
+	}
+
+	// Problems send the form back with what was typed
+	bad := url.Values{"tournamentID": {"1"}, "name": {"Bob Jones"}, "skill": {"7"}, "roles": {"Queen"}, "email": {"bob@example.com"}}
+	if body := client.post("/add-player", bad, http.StatusBadRequest).Body.String(); !strings.Contains(body, "Skill level needs a number from 1 to 5.") || !strings.Contains(body, "Bob Jones") {
+		t.Errorf("the form with problems doesn't explain them or keep the name:\n%v", body)
+	}
+
+	// A valid registration is confirmed with its answers
+	if body := client.post("/add-player", form, http.StatusOK).Body.String(); !strings.Contains(body, "Queen, Speed Warrior") {
+		t.Errorf("the confirmation doesn't list the answers:\n%v", body)
+	}
+
+	// Registering twice is turned away
+	if body := client.post("/add-player", form, http.StatusBadRequest).Body.String(); !strings.Contains(body, "already registered") {
+		t.Errorf("a second registration wasn't turned away:\n%v", body)
+	}
+
+	// So is registering after registration closes
+	registrationCloses["1"] = time.Now().Add(-time.Minute)
+	form.Set("name", "Cat")
+	form.Set("email", "cat@example.com")
+	client.post("/add-player", form, http.StatusForbidden)
+
+	players, err := hm.Players(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 2 {
+		t.Errorf("the tournament has %v players, want Alice and Bob", len(players))
+	}
+}
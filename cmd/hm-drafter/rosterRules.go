@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How roster rules are enforced on picks
+const (
+	warnRoster  = "warn"  // The pick needs confirming
+	blockRoster = "block" // The pick is refused
+)
+
+// rosterRulePattern matches one rule as organizers write it, e.g. "at least 1 roles: Objective Runner", "at most 2 roles: only Vanilla Warrior, Speed Warrior" or "evenly flexible: Yes"
+var rosterRulePattern = regexp.MustCompile(`(?i)^(?:(at least|at most|exactly)\s+(\d+)|(evenly))\s+([\w-]+)\s*:\s*(only\s+)?(.+)$`)
+
+// RosterRule is one requirement every team's roster has to meet, e.g. at least one player willing to play objective. Rules work on any form field, so they can also spread out new players or players with flexible attendance.
+type RosterRule struct {
+	Field  string   // Form field slug, e.g. "roles" or "flexible"
+	Values []string // Answers that count towards the rule
+	Only   bool     // Only count players whose every answer is one of Values, e.g. players who only play warrior
+	Even   bool     // Spread the players who count as evenly as possible instead of using Min and Max
+	Min    int
+	Max    int // -1 for no maximum
+}
+
+// ParseRosterRules reads rules one per line, checking them against the tournament's form fields. Blank lines and lines starting with # are skipped.
+func ParseRosterRules(text string, formFields []FormField) (rules []RosterRule, err error) {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		match := rosterRulePattern.FindStringSubmatch(line)
+		if match == nil {
+			return nil, fmt.Errorf("can't read roster rule %q, write it like \"at least 1 roles: Objective Runner\" or \"evenly flexible: Yes\"", line)
+		}
+
+		n, _ := strconv.Atoi(match[2])
+		rule := RosterRule{Field: match[4], Values: splitList(match[6]), Only: match[5] != "", Even: match[3] != "", Min: n, Max: n}
+		switch strings.ToLower(match[1]) {
+		case "at least":
+			rule.Max = -1
+		case "at most":
+			rule.Min = 0
+		}
+
+		field, ok := findFormField(formFields, rule.Field)
+		if !ok {
+			return nil, fmt.Errorf("roster rule %q: %q isn't one of this tournament's form fields", line, rule.Field)
+		}
+		for i, value := range rule.Values {
+			if len(field.Choices) == 0 {
+				continue
+			}
+			choice, ok := findChoice(field.Choices, value)
+			if !ok {
+				return nil, fmt.Errorf("roster rule %q: %q isn't one of the choices for %v", line, value, field.Label())
+			}
+			rule.Values[i] = choice
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// findChoice looks up a choice ignoring case, returning it as the field spells it
+func findChoice(choices []string, value string) (string, bool) {
+	for _, choice := range choices {
+		if strings.EqualFold(choice, value) {
+			return choice, true
+		}
+	}
+	return "", false
+}
+
+// String writes the rule the way organizers enter it
+func (r RosterRule) String() string {
+	amount := fmt.Sprintf("exactly %d", r.Min)
+	switch {
+	case r.Even:
+		amount = "evenly"
+	case r.Max < 0:
+		amount = fmt.Sprintf("at least %d", r.Min)
+	case r.Min == 0:
+		amount = fmt.Sprintf("at most %d", r.Max)
+	}
+
+	return amount + " " + r.Label()
+}
+
+// Label describes the players the rule counts, e.g. "roles: only Vanilla Warrior, Speed Warrior"
+func (r RosterRule) Label() string {
+	only := ""
+	if r.Only {
+		only = "only "
+	}
+	return fmt.Sprintf("%v: %v%v", r.Field, only, strings.Join(r.Values, ", "))
+}
+
+// Matches reports whether a player counts towards the rule
+func (r RosterRule) Matches(player Player) bool {
+	value := player.Field(r.Field)
+	if !r.Only {
+		for _, v := range r.Values {
+			if value.Has(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	items := value.Items()
+	for _, item := range items {
+		if _, ok := findChoice(r.Values, item); !ok {
+			return false
+		}
+	}
+	return len(items) > 0
+}
+
+// count is how many of the players count towards the rule
+func (r RosterRule) count(players []Player) (n int) {
+	for _, player := range players {
+		if r.Matches(player) {
+			n++
+		}
+	}
+	return n
+}
+
+// bounds returns how many players who count each team should have. For an even spread that depends on how many there are in all (total) and how many teams they're spread over.
+func (r RosterRule) bounds(total, teams int) (min, max int) {
+	if !r.Even {
+		return r.Min, r.Max
+	}
+	if teams == 0 {
+		return 0, -1
+	}
+	return total / teams, (total + teams - 1) / teams
+}
+
+// status is where a team stands on the rule
+func (r RosterRule) status(players []Player, total, teams int) RuleStatus {
+	n := r.count(players)
+	min, max := r.bounds(total, teams)
+
+	status := RuleStatus{Rule: r, Count: n, Min: min, Max: max}
+	switch {
+	case n < min:
+		status.Off = min - n
+	case max >= 0 && n > max:
+		status.Off = n - max
+	}
+	status.Met = status.Off == 0
+	return status
+}
+
+// RosterRulesText is the draft's rules as organizers write them
+func (d *Draft) RosterRulesText() string {
+	var lines []string
+	for _, rule := range d.RosterRules {
+		lines = append(lines, rule.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SetRosterRules replaces the draft's roster rules and how they're enforced
+func (d *Draft) SetRosterRules(text, enforcement string) error {
+	rules, err := ParseRosterRules(text, d.FormFields)
+	if err != nil {
+		return err
+	}
+	if enforcement != blockRoster {
+		enforcement = warnRoster
+	}
+
+	d.RosterRules = rules
+	d.RosterEnforcement = enforcement
+	log.Printf("Draft %v: roster rules (%v): %v", d.ID, enforcement, rules)
+
+	// Coverage on every open page changes with the rules
+	d.publish("reload", nil)
+	return nil
+}
+
+// rosterState is the draft as the roster rules see it: each captain's roster, how many picks they have left and who's still in the pool
+type rosterState struct {
+	teams     []TeamInfo // Indexed like the draft order
+	picksLeft []int
+	pool      []Player
+}
+
+// rosterState returns the draft's rosters as they are now
+func (d *Draft) rosterState() rosterState {
+	state := rosterState{pool: d.DraftPlayers}
+
+	// The picks still to come
+	sequence := d.sequence()
+	state.picksLeft = make([]int, len(d.DraftOrder))
+	if len(d.Picks) < len(sequence) {
+		for _, slot := range sequence[len(d.Picks):] {
+			if slot.CaptainIndex < len(state.picksLeft) {
+				state.picksLeft[slot.CaptainIndex]++
+			}
+		}
+	}
+
+	for _, captain := range d.DraftOrder {
+		team := TeamInfo{Name: captain.Name}
+		teamID := CaptainTeamID(d.Teams, captain)
+		for _, t := range d.Teams {
+			if t.ID == teamID {
+				team = t
+			}
+		}
+		state.teams = append(state.teams, team)
+	}
+	return state
+}
+
+// picksLeftText reads "no picks", "1 pick" or "n picks"
+func picksLeftText(n int) string {
+	switch n {
+	case 0:
+		return "no picks"
+	case 1:
+		return "1 pick"
+	}
+	return fmt.Sprintf("%d picks", n)
+}
+
+// withPick returns the state after the captain at index picks player
+func (s rosterState) withPick(index int, player Player) rosterState {
+	next := rosterState{
+		teams:     append([]TeamInfo(nil), s.teams...),
+		picksLeft: append([]int(nil), s.picksLeft...),
+		pool:      RemoveDraftedPlayers(s.pool, player.ID),
+	}
+	next.teams[index].Players = append(append([]Player(nil), s.teams[index].Players...), player)
+	if next.picksLeft[index] > 0 {
+		next.picksLeft[index]--
+	}
+	return next
+}
+
+// total is how many players in the whole draft count towards the rule, drafted or not
+func (s rosterState) total(rule RosterRule) int {
+	total := rule.count(s.pool)
+	for _, team := range s.teams {
+		total += rule.count(team.Players)
+	}
+	return total
+}
+
+// problems lists every rule a team can no longer meet, keyed so the same problem can be spotted before and after a pick
+func (s rosterState) problems(rules []RosterRule) (keys []string, problems map[string]string) {
+	problems = make(map[string]string)
+	add := func(key, problem string) {
+		keys = append(keys, key)
+		problems[key] = problem
+	}
+
+	for r, rule := range rules {
+		min, max := rule.bounds(s.total(rule), len(s.teams))
+
+		short := 0
+		for i, team := range s.teams {
+			have := rule.count(team.Players)
+			if max >= 0 && have > max {
+				add(fmt.Sprintf("%d/%d/max", r, i), fmt.Sprintf("%v would have %d players with %v, the most allowed is %d.", team.Name, have, rule.Label(), max))
+			}
+			if need := min - have; need > 0 {
+				if need > s.picksLeft[i] {
+					add(fmt.Sprintf("%d/%d/min", r, i), fmt.Sprintf("%v needs %d more players with %v but has %v left.", team.Name, need, rule.Label(), picksLeftText(s.picksLeft[i])))
+				}
+				short += need
+			}
+		}
+
+		// Every team still needing one has to find them in the same pool
+		if left := rule.count(s.pool); short > left {
+			add(fmt.Sprintf("%d/pool", r), fmt.Sprintf("Teams still need %d players with %v but only %d are left.", short, rule.Label(), left))
+		}
+	}
+	return keys, problems
+}
+
+// RosterProblems returns the roster rules the current captain picking player would leave a team unable to meet, given the picks left and who's still in the pool. Problems the draft already had aren't counted against the pick.
+func (d *Draft) RosterProblems(player Player) []string {
+	index := d.CurrentSlot().CaptainIndex
+	if len(d.RosterRules) == 0 || d.Mode == auctionMode || index >= len(d.DraftOrder) {
+		return nil
+	}
+
+	before := d.rosterState()
+	_, already := before.problems(d.RosterRules)
+	keys, after := before.withPick(index, player).problems(d.RosterRules)
+
+	var problems []string
+	for _, key := range keys {
+		if _, ok := already[key]; !ok {
+			problems = append(problems, after[key])
+		}
+	}
+	return problems
+}
+
+// fitsRoster reports whether picking player breaks none of the roster rules
+func (d *Draft) fitsRoster(player Player) bool {
+	return len(d.RosterProblems(player)) == 0
+}
+
+// RuleStatus is how one team is doing on one roster rule
+type RuleStatus struct {
+	Rule  RosterRule
+	Count int
+	Min   int
+	Max   int // -1 for no maximum
+	Off   int // How many players short of or over the rule the team is
+	Met   bool
+}
+
+// Target describes what the rule asks of the team, e.g. "1+", "at most 2" or "2-3"
+func (s RuleStatus) Target() string {
+	switch {
+	case s.Max < 0:
+		return fmt.Sprintf("%d+", s.Min)
+	case s.Min == s.Max:
+		return strconv.Itoa(s.Min)
+	case s.Min == 0:
+		return fmt.Sprintf("at most %d", s.Max)
+	}
+	return fmt.Sprintf("%d-%d", s.Min, s.Max)
+}
+
+// ruleStatuses is where a team stands on each rule, out of all the players the rules are spread across
+func ruleStatuses(rules []RosterRule, team []Player, everyone []Player, teams int) (statuses []RuleStatus) {
+	for _, rule := range rules {
+		statuses = append(statuses, rule.status(team, rule.count(everyone), teams))
+	}
+	return statuses
+}
+
+// ChoiceCount is how many players on a team gave one answer, e.g. how many play Queen
+type ChoiceCount struct {
+	Choice string
+	Count  int
+}
+
+// TeamCoverage is a team's role coverage for the drafting page
+type TeamCoverage struct {
+	Team  TeamInfo
+	Roles []ChoiceCount
+	Rules []RuleStatus
+}
+
+// RoleCoverage returns how many players on each captain's team play each role, and where each team stands on the roster rules
+func (d *Draft) RoleCoverage() (coverage []TeamCoverage) {
+	roles, hasRoles := findFormField(d.FormFields, "roles")
+
+	state := d.rosterState()
+	everyone := append([]Player(nil), state.pool...)
+	for _, team := range state.teams {
+		everyone = append(everyone, team.Players...)
+	}
+
+	for _, team := range state.teams {
+		tc := TeamCoverage{Team: team, Rules: ruleStatuses(d.RosterRules, team.Players, everyone, len(state.teams))}
+		if hasRoles {
+			for _, role := range roles.Choices {
+				tc.Roles = append(tc.Roles, ChoiceCount{Choice: role, Count: CountPlayers(team.Players, "roles", role)})
+			}
+		}
+		coverage = append(coverage, tc)
+	}
+	return coverage
+}
+
+// checkRosterPick stops a pick that breaks the roster rules, showing what it would break. Warnings can be confirmed by posting the pick again with confirmRoster set, blocked picks can't. It returns false if it's responded instead of letting the pick through.
+func checkRosterPick(c *gin.Context, d *Draft, playerID float64) bool {
+	player, ok := d.poolPlayer(playerID)
+	if !ok {
+		// Let the pick fail the usual way
+		return true
+	}
+
+	problems := d.RosterProblems(player)
+	if len(problems) == 0 || (d.RosterEnforcement != blockRoster && c.PostForm("confirmRoster") != "") {
+		return true
+	}
+
+	log.Printf("Draft %v: %v picking %v breaks roster rules: %v", d.ID, d.CurrentCaptain().Name, player.Name, problems)
+
+	status := http.StatusOK
+	if d.RosterEnforcement == blockRoster {
+		status = http.StatusConflict
+	}
+	c.HTML(status, "roster-warning.html", d.pageData(gin.H{
+		"player":   player,
+		"captain":  d.CurrentCaptain(),
+		"problems": problems,
+		"blocked":  d.RosterEnforcement == blockRoster,
+		"action":   c.Request.URL.Path,
+		"back":     c.Request.Referer(),
+	}))
+	return false
+}
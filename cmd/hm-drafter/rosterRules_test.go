@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// rosterFields are the form fields the roster rule tests write rules against
+var rosterFields = []FormField{
+	{Slug: "roles", Type: multiselectField, Choices: []string{"Queen", "Speed Warrior", "Vanilla Warrior", "Objective Runner"}},
+	{Slug: "flexible", Type: checkboxField},
+	{Slug: "skill", Type: numberField},
+}
+
+// rosterPlayer is a player with the given roles and flexibility
+func rosterPlayer(id float64, flexible bool, roles ...string) Player {
+	return Player{ID: id, FormFields: map[string]FieldValue{"roles": ListValue(roles), "flexible": BoolValue(flexible)}}
+}
+
+func TestParseRosterRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []RosterRule
+		wantErr bool
+	}{
+		{
+			name: "at least",
+			text: "at least 1 roles: objective runner",
+			want: []RosterRule{{Field: "roles", Values: []string{"Objective Runner"}, Min: 1, Max: -1}},
+		},
+		{
+			name: "at most only",
+			text: "At most 2 roles: only Vanilla Warrior, Speed Warrior",
+			want: []RosterRule{{Field: "roles", Values: []string{"Vanilla Warrior", "Speed Warrior"}, Only: true, Min: 0, Max: 2}},
+		},
+		{
+			name: "exactly and evenly, skipping blanks and comments",
+			text: "# one queen each\nexactly 1 roles: Queen\n\nevenly flexible: Yes",
+			want: []RosterRule{
+				{Field: "roles", Values: []string{"Queen"}, Min: 1, Max: 1},
+				{Field: "flexible", Values: []string{"Yes"}, Even: true},
+			},
+		},
+		{name: "unreadable", text: "lots of roles: Queen", wantErr: true},
+		{name: "unknown field", text: "at least 1 shoe: Left", wantErr: true},
+		{name: "unknown choice", text: "at least 1 roles: Drone", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRosterRules(tt.text, rosterFields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRosterRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule   string
+		player Player
+		want   bool
+	}{
+		{rule: "at least 1 roles: Queen", player: rosterPlayer(1, false, "Queen", "Speed Warrior"), want: true},
+		{rule: "at least 1 roles: Queen", player: rosterPlayer(1, false, "Speed Warrior"), want: false},
+		{rule: "at least 1 roles: Queen", player: rosterPlayer(1, false), want: false},
+		{rule: "at most 2 roles: only Vanilla Warrior, Speed Warrior", player: rosterPlayer(1, false, "Speed Warrior", "Vanilla Warrior"), want: true},
+		{rule: "at most 2 roles: only Vanilla Warrior, Speed Warrior", player: rosterPlayer(1, false, "Speed Warrior", "Queen"), want: false},
+		{rule: "at most 2 roles: only Vanilla Warrior, Speed Warrior", player: rosterPlayer(1, false), want: false},
+		{rule: "evenly flexible: Yes", player: rosterPlayer(1, true), want: true},
+		{rule: "evenly flexible: Yes", player: rosterPlayer(1, false), want: false},
+		{rule: "evenly flexible: No", player: rosterPlayer(1, false), want: true},
+	}
+
+	for _, tt := range tests {
+		rules, err := ParseRosterRules(tt.rule, rosterFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rules[0].Matches(tt.player); got != tt.want {
+			t.Errorf("%q matching %v: got %v, want %v", tt.rule, tt.player.Field("roles"), got, tt.want)
+		}
+	}
+}
+
+func TestRosterRuleStatus(t *testing.T) {
+	queen := rosterPlayer(1, false, "Queen")
+	warrior := rosterPlayer(2, false, "Speed Warrior")
+
+	tests := []struct {
+		rule   string
+		team   []Player
+		total  int
+		teams  int
+		want   RuleStatus
+		target string
+	}{
+		{rule: "at least 1 roles: Queen", team: []Player{warrior}, want: RuleStatus{Count: 0, Min: 1, Max: -1, Off: 1}, target: "1+"},
+		{rule: "at least 1 roles: Queen", team: []Player{queen, warrior}, want: RuleStatus{Count: 1, Min: 1, Max: -1, Met: true}, target: "1+"},
+		{rule: "at most 1 roles: Queen", team: []Player{queen, queen}, want: RuleStatus{Count: 2, Min: 0, Max: 1, Off: 1}, target: "at most 1"},
+		{rule: "exactly 1 roles: Queen", team: []Player{queen}, want: RuleStatus{Count: 1, Min: 1, Max: 1, Met: true}, target: "1"},
+		{rule: "evenly roles: Queen", team: []Player{queen}, total: 5, teams: 2, want: RuleStatus{Count: 1, Min: 2, Max: 3, Off: 1}, target: "2-3"},
+		{rule: "evenly roles: Queen", team: []Player{queen, queen}, total: 4, teams: 2, want: RuleStatus{Count: 2, Min: 2, Max: 2, Met: true}, target: "2"},
+	}
+
+	for _, tt := range tests {
+		rules, err := ParseRosterRules(tt.rule, rosterFields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.want.Rule = rules[0]
+		got := rules[0].status(tt.team, tt.total, tt.teams)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %+v, want %+v", tt.rule, got, tt.want)
+		}
+		if got.Target() != tt.target {
+			t.Errorf("%q: target %q, want %q", tt.rule, got.Target(), tt.target)
+		}
+	}
+}
+
+func TestRosterProblems(t *testing.T) {
+	tests := []struct {
+		name      string
+		rule      string
+		picks     []float64 // Made before the candidate, snake order between the 2 captains
+		candidate float64
+		want      int
+	}{
+		{name: "taking a queen", rule: "at least 1 roles: Queen", candidate: 5},
+		{name: "a warrior with picks to spare", rule: "at least 1 roles: Queen", candidate: 3},
+		{name: "last pick without a queen", rule: "at least 1 roles: Queen", picks: []float64{5, 3}, candidate: 4, want: 1},
+		{name: "last pick on the last queen", rule: "at least 1 roles: Queen", picks: []float64{5, 3}, candidate: 6},
+		{name: "one queen too many", rule: "at most 1 roles: Queen", picks: []float64{5, 3, 4}, candidate: 6, want: 1},
+		{name: "spreading queens evenly", rule: "evenly roles: Queen", picks: []float64{5}, candidate: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// Two captains and a pool of two warriors (3, 4) and two queens (5, 6)
+			d := localDraft(t, 6, 2)
+			d.FormFields = rosterFields
+			roles := map[float64]string{1: "Speed Warrior", 2: "Speed Warrior", 3: "Speed Warrior", 4: "Vanilla Warrior", 5: "Queen", 6: "Queen"}
+			for _, list := range [][]Player{d.Players, d.DraftPlayers} {
+				for i := range list {
+					list[i].FormFields["roles"] = ListValue([]string{roles[list[i].ID]})
+				}
+			}
+			if err := d.SetRosterRules(tt.rule, blockRoster); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, id := range tt.picks {
+				if _, err := d.PickPlayer(ctx, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			candidate, _ := d.poolPlayer(tt.candidate)
+			if got := d.RosterProblems(candidate); len(got) != tt.want {
+				t.Errorf("got problems %q, want %d", got, tt.want)
+			}
+		})
+	}
+}
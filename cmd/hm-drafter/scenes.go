@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind"
+)
+
+// sceneCacheTTL is how long the scene list from HiveMind is reused before it's fetched again
+const sceneCacheTTL = 10 * time.Minute
+
+var (
+	// defaultScene is the scene the lobby shows when the URL doesn't pick one, set with -scene or SCENE
+	defaultScene = "kqpdx"
+
+	// brandings overrides the look of each scene, keyed by scene name. Set with -branding.
+	brandings = map[string]Branding{
+		"kqpdx": {Title: "Portland Mixer Drafting", Background: "rosybrown", Panel: "#C9A5A5", Text: "#3A3B3C"},
+	}
+
+	scenes sceneDirectory
+)
+
+// Branding is how a scene's pages look: the title in every tab, the page colors and an optional logo
+type Branding struct {
+	Scene      string `json:"-"`
+	Title      string `json:"title"`
+	Logo       string `json:"logo"`       // URL of an image shown at the top of the lobby and draft pages
+	Background string `json:"background"` // Page background color
+	Panel      string `json:"panel"`      // Background of boxes, cards and buttons
+	Text       string `json:"text"`
+}
+
+// LoadBrandings reads per-scene branding from a JSON file keyed by scene name, e.g. {"kqsea": {"title": "Seattle Mixer Drafting", "logo": "/static/kqsea.png"}}. Scenes in the file replace the built-in branding.
+func LoadBrandings(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var loaded map[string]Branding
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("parsing branding %s: %w", path, err)
+	}
+	for scene, branding := range loaded {
+		brandings[strings.ToLower(scene)] = branding
+	}
+	return nil
+}
+
+// sceneDirectory caches HiveMind's scene list so every page doesn't have to ask for it
+type sceneDirectory struct {
+	mu      sync.Mutex
+	scenes  []hivemind.Scene
+	fetched time.Time
+}
+
+// List returns every HiveMind scene sorted by display name, fetching them if the cache is empty or stale. A stale list is still returned if HiveMind can't be reached. The lock is only held to read and update the cache, never during the request to HiveMind.
+func (s *sceneDirectory) List(ctx context.Context) ([]hivemind.Scene, error) {
+	s.mu.Lock()
+	cached, fresh := s.scenes, s.scenes != nil && time.Since(s.fetched) < sceneCacheTTL
+	s.mu.Unlock()
+	if fresh {
+		return cached, nil
+	}
+
+	fetched, err := hm.Scenes(ctx)
+	if err != nil {
+		if cached != nil {
+			log.Printf("Refreshing scenes failed, using the cached list: %v", err)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("fetching scenes: %w", err)
+	}
+
+	sort.Slice(fetched, func(i, j int) bool {
+		return strings.ToLower(sceneLabel(fetched[i])) < strings.ToLower(sceneLabel(fetched[j]))
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenes = fetched
+	s.fetched = time.Now()
+	return fetched, nil
+}
+
+// cached looks up a scene in whatever list was last fetched, without going to HiveMind
+func (s *sceneDirectory) cached(name string) (hivemind.Scene, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, scene := range s.scenes {
+		if strings.EqualFold(scene.Name, name) {
+			return scene, true
+		}
+	}
+	return hivemind.Scene{}, false
+}
+
+func sceneLabel(scene hivemind.Scene) string {
+	if scene.DisplayName != "" {
+		return scene.DisplayName
+	}
+	return scene.Name
+}
+
+// normalizeScene cleans up a scene name from a URL or form, falling back to the default scene
+func normalizeScene(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return defaultScene
+	}
+	return name
+}
+
+// BrandingFor works out how a scene's pages look. Configured branding wins, anything it leaves out comes from the scene's HiveMind colors, and the app's own look fills in the rest.
+func BrandingFor(name string) Branding {
+	name = normalizeScene(name)
+	branding := brandings[name]
+	branding.Scene = name
+
+	if hmScene, ok := scenes.cached(name); ok {
+		if branding.Title == "" {
+			branding.Title = sceneLabel(hmScene) + " Mixer Drafting"
+		}
+		if branding.Background == "" {
+			branding.Background = hmScene.BackgroundColor
+		}
+		if branding.Text == "" {
+			branding.Text = hmScene.ForegroundColor
+		}
+		if branding.Panel == "" && hmScene.BackgroundColor != "" {
+			branding.Panel = "#ffffff59" // A light wash over the scene color
+		}
+	}
+
+	if branding.Title == "" {
+		branding.Title = name + " Mixer Drafting"
+	}
+	if branding.Background == "" {
+		branding.Background = "rosybrown"
+	}
+	if branding.Panel == "" {
+		branding.Panel = "#C9A5A5"
+	}
+	if branding.Text == "" {
+		branding.Text = "#3A3B3C"
+	}
+	return branding
+}
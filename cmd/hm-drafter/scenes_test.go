@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind"
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+// useBrandings swaps in configured branding and a cached HiveMind scene list for the rest of the test
+func useBrandings(t *testing.T, configured map[string]Branding, hmScenes []hivemind.Scene) {
+	t.Helper()
+
+	oldBrandings := brandings
+	scenes.mu.Lock()
+	oldScenes, oldFetched := scenes.scenes, scenes.fetched
+	scenes.scenes, scenes.fetched = hmScenes, time.Now()
+	scenes.mu.Unlock()
+	brandings = configured
+
+	t.Cleanup(func() {
+		brandings = oldBrandings
+		scenes.mu.Lock()
+		scenes.scenes, scenes.fetched = oldScenes, oldFetched
+		scenes.mu.Unlock()
+	})
+}
+
+func TestBrandingFor(t *testing.T) {
+	useBrandings(t,
+		map[string]Branding{
+			"kqpdx": {Title: "Portland Mixer Drafting", Logo: "/static/pdx.png", Background: "rosybrown", Panel: "#C9A5A5", Text: "#3A3B3C"},
+			"kqsea": {Title: "Seattle Draft Night"},
+		},
+		[]hivemind.Scene{
+			{Name: "kqpdx", DisplayName: "Portland", BackgroundColor: "#bc8f8f", ForegroundColor: "#111111"},
+			{Name: "kqsea", DisplayName: "Seattle", BackgroundColor: "#2e5e4e", ForegroundColor: "#eeeeee"},
+			{Name: "kqchi", DisplayName: "Chicago", BackgroundColor: "#002244", ForegroundColor: "#ffffff"},
+			{Name: "kqbare"},
+		},
+	)
+
+	tests := []struct {
+		name  string
+		scene string
+		want  Branding
+	}{
+		{
+			name:  "configured branding wins",
+			scene: "KQPDX",
+			want:  Branding{Scene: "kqpdx", Title: "Portland Mixer Drafting", Logo: "/static/pdx.png", Background: "rosybrown", Panel: "#C9A5A5", Text: "#3A3B3C"},
+		},
+		{
+			name:  "HiveMind colors fill in what's not configured",
+			scene: "kqsea",
+			want:  Branding{Scene: "kqsea", Title: "Seattle Draft Night", Background: "#2e5e4e", Panel: "#ffffff59", Text: "#eeeeee"},
+		},
+		{
+			name:  "HiveMind scene without configured branding",
+			scene: "kqchi",
+			want:  Branding{Scene: "kqchi", Title: "Chicago Mixer Drafting", Background: "#002244", Panel: "#ffffff59", Text: "#ffffff"},
+		},
+		{
+			name:  "HiveMind scene without colors",
+			scene: "kqbare",
+			want:  Branding{Scene: "kqbare", Title: "kqbare Mixer Drafting", Background: "rosybrown", Panel: "#C9A5A5", Text: "#3A3B3C"},
+		},
+		{
+			name:  "scene HiveMind doesn't know",
+			scene: "kqnowhere",
+			want:  Branding{Scene: "kqnowhere", Title: "kqnowhere Mixer Drafting", Background: "rosybrown", Panel: "#C9A5A5", Text: "#3A3B3C"},
+		},
+		{
+			name:  "no scene is the default scene",
+			scene: " ",
+			want:  Branding{Scene: defaultScene, Title: "Portland Mixer Drafting", Logo: "/static/pdx.png", Background: "rosybrown", Panel: "#C9A5A5", Text: "#3A3B3C"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BrandingFor(tt.scene); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBrandings(t *testing.T) {
+	builtIn := Branding{Title: "Portland Mixer Drafting", Background: "rosybrown"}
+
+	tests := []struct {
+		name    string
+		file    string // Written to a temp file, or no file when empty
+		want    map[string]Branding
+		wantErr bool
+	}{
+		{
+			name: "adds scenes and lowercases their names",
+			file: `{"KQSEA": {"title": "Seattle Draft Night", "logo": "/static/kqsea.png", "panel": "#2e5e4e80"}}`,
+			want: map[string]Branding{
+				"kqpdx": builtIn,
+				"kqsea": {Title: "Seattle Draft Night", Logo: "/static/kqsea.png", Panel: "#2e5e4e80"},
+			},
+		},
+		{
+			name: "replaces built-in branding",
+			file: `{"kqpdx": {"title": "PDX"}}`,
+			want: map[string]Branding{"kqpdx": {Title: "PDX"}},
+		},
+		{name: "unreadable JSON", file: `{"kqsea": "blue"}`, wantErr: true},
+		{name: "missing file", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBrandings(t, map[string]Branding{"kqpdx": builtIn}, nil)
+
+			path := filepath.Join(t.TempDir(), "branding.json")
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := LoadBrandings(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(brandings, tt.want) {
+				t.Errorf("got %+v, want %+v", brandings, tt.want)
+			}
+		})
+	}
+}
+
+func TestSceneList(t *testing.T) {
+	serveFakeHiveMind(t, &hivemindtest.Fixtures{Scenes: []map[string]interface{}{
+		{"id": 1, "name": "kqsea", "display_name": "Seattle"},
+		{"id": 2, "name": "kqpdx", "display_name": "Portland"},
+		{"id": 3, "name": "kqabc"},
+	}})
+	// Start from an empty scene cache
+	useBrandings(t, brandings, nil)
+	ctx := context.Background()
+
+	list, err := scenes.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, scene := range list {
+		names = append(names, scene.Name)
+	}
+	if want := []string{"kqabc", "kqpdx", "kqsea"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got scenes %v, want %v sorted by label", names, want)
+	}
+
+	// Once HiveMind can't be reached, a stale list is still better than nothing
+	hm = hivemind.NewClient("", hivemind.WithBaseURL("http://127.0.0.1:1"), hivemind.WithRetries(0, 0))
+	scenes.mu.Lock()
+	scenes.fetched = time.Now().Add(-2 * sceneCacheTTL)
+	scenes.mu.Unlock()
+	if stale, err := scenes.List(ctx); err != nil || len(stale) != 3 {
+		t.Errorf("got %v scenes and error %v from a stale cache, want the 3 cached ones", len(stale), err)
+	}
+
+	scenes.mu.Lock()
+	scenes.scenes = nil
+	scenes.mu.Unlock()
+	if _, err := scenes.List(ctx); err == nil {
+		t.Error("listing scenes with nothing cached and HiveMind down didn't return an error")
+	}
+}
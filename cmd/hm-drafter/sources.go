@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/imandradesign/hm-drafter/hivemind"
+)
+
+const (
+	hivemindSource = "hivemind"
+	csvSource      = "csv"
+	jsonSource     = "json"
+)
+
+// PlayerSource loads the registration form fields and players a draft starts from. Every source produces the same Player/FormFields shape as HiveMind, so the rest of the draft can't tell them apart.
+type PlayerSource interface {
+	// Name is saved on the draft: "hivemind", "csv" or "json"
+	Name() string
+	Load(ctx context.Context) (formFields []FormField, players []Player, err error)
+}
+
+// HiveMindSource loads a tournament's registrations from HiveMind
+type HiveMindSource struct {
+	TournamentID string
+}
+
+func (HiveMindSource) Name() string { return hivemindSource }
+
+func (s HiveMindSource) Load(ctx context.Context) ([]FormField, []Player, error) {
+	formFields, err := GetFormFields(ctx, s.TournamentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	players, err := GetPlayersData(ctx, s.TournamentID, formFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return formFields, players, nil
+}
+
+// playerColumns are the CSV columns that fill in Player fields instead of form fields
+var playerColumns = []string{"id", "name", "pronouns", "scene"}
+
+// CSVSource reads players from a spreadsheet export with a header row. Mapping renames columns, keyed by header: to "name", "pronouns", "scene" or "id" for the player's own fields, to a slug like "skill" or "roles" for a form field, or to "-" to skip the column. Unmapped columns become form fields named after their header.
+type CSVSource struct {
+	Data    []byte
+	Mapping map[string]string
+}
+
+func (CSVSource) Name() string { return csvSource }
+
+func (s CSVSource) Load(ctx context.Context) (formFields []FormField, players []Player, err error) {
+	reader := csv.NewReader(bytes.NewReader(s.Data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading CSV: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, nil, fmt.Errorf("the CSV needs a header row and at least one player")
+	}
+
+	header := rows[0]
+	targets := make([]string, len(header))
+	used := make(map[string]bool)
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		header[i] = column
+		target, mapped := lookupMapping(s.Mapping, column)
+		if !mapped {
+			target = fieldSlug(column)
+		}
+		if target == "-" || target == "" {
+			continue
+		}
+
+		// Two columns can't feed the same field
+		for base, n := target, 2; used[target]; n++ {
+			target = base + "_" + strconv.Itoa(n)
+		}
+		used[target] = true
+		targets[i] = target
+
+		if !containsString(playerColumns, target) {
+			formFields = append(formFields, NewFormField(column, target, column, "", nil, false))
+		}
+	}
+	if !used["name"] {
+		return nil, nil, fmt.Errorf("the CSV needs a name column, map one with e.g. \"Full Name = name\"")
+	}
+
+	for n, row := range rows[1:] {
+		data := make(map[string]interface{})
+		for i, value := range row {
+			if i >= len(targets) || targets[i] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			if containsString(playerColumns, targets[i]) {
+				data[targets[i]] = value
+			} else {
+				data[header[i]] = value
+			}
+		}
+		if data["name"] == "" || data["name"] == nil {
+			continue
+		}
+
+		// Rows without an ID are numbered in the order they appear
+		id, err := strconv.ParseFloat(safeString(data["id"]), 64)
+		if err != nil || id == 0 {
+			id = float64(n + 1)
+		}
+		data["id"] = id
+
+		players = append(players, ParsePlayers(data, formFields))
+	}
+
+	if err := checkUniqueIDs(players); err != nil {
+		return nil, nil, err
+	}
+	return formFields, players, nil
+}
+
+// JSONSource reads players from JSON shaped like HiveMind's API: {"formFields": [{"field_name", "field_slug", "field_description", "field_type", "choices"}], "players": [...]}. Players can also carry their answers in a "form_fields" object keyed by slug, in which case formFields can be left out.
+type JSONSource struct {
+	Data []byte
+}
+
+func (JSONSource) Name() string { return jsonSource }
+
+func (s JSONSource) Load(ctx context.Context) (formFields []FormField, players []Player, err error) {
+	var file struct {
+		FormFields []hivemind.FormField     `json:"formFields"`
+		Players    []map[string]interface{} `json:"players"`
+	}
+
+	// A bare list of players is fine too
+	if trimmed := bytes.TrimSpace(s.Data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &file.Players)
+	} else {
+		err = json.Unmarshal(trimmed, &file)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading JSON: %w", err)
+	}
+
+	for _, field := range file.FormFields {
+		formFields = append(formFields, formFieldFromHiveMind(field))
+	}
+
+	// Answers keyed by slug are flattened so ParsePlayers finds them the same way it finds HiveMind's field names
+	slugs := make(map[string]bool)
+	for _, data := range file.Players {
+		answers, _ := data["form_fields"].(map[string]interface{})
+		for slug, value := range answers {
+			data[slug] = value
+			slugs[slug] = true
+		}
+	}
+	if len(formFields) == 0 {
+		var sorted []string
+		for slug := range slugs {
+			sorted = append(sorted, slug)
+		}
+		sort.Strings(sorted)
+		for _, slug := range sorted {
+			formFields = append(formFields, NewFormField(slug, slug, slug, "", nil, false))
+		}
+	}
+
+	for n, data := range file.Players {
+		player := ParsePlayers(data, formFields)
+		if player.Name == "" {
+			continue
+		}
+		if player.ID == 0 {
+			player.ID = float64(n + 1)
+		}
+		players = append(players, player)
+	}
+
+	if len(players) == 0 {
+		return nil, nil, fmt.Errorf("the JSON doesn't have any players")
+	}
+	if err := checkUniqueIDs(players); err != nil {
+		return nil, nil, err
+	}
+	return formFields, players, nil
+}
+
+// JSONFileSource reads a JSONSource from a file on disk
+type JSONFileSource struct {
+	Path string
+}
+
+func (JSONFileSource) Name() string { return jsonSource }
+
+func (s JSONFileSource) Load(ctx context.Context) ([]FormField, []Player, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return JSONSource{Data: data}.Load(ctx)
+}
+
+// fileSource picks a source for an uploaded or local file by its extension, falling back to sniffing for JSON
+func fileSource(filename string, data []byte, mapping map[string]string) PlayerSource {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return JSONSource{Data: data}
+	case ".csv":
+		return CSVSource{Data: data, Mapping: mapping}
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return JSONSource{Data: data}
+	}
+	return CSVSource{Data: data, Mapping: mapping}
+}
+
+// ParseColumnMapping reads one "Column Header = target" per line
+func ParseColumnMapping(text string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		column, target, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid column mapping %q, expected \"Column Header = field\"", line)
+		}
+		mapping[strings.TrimSpace(column)] = strings.TrimSpace(target)
+	}
+	return mapping, nil
+}
+
+// lookupMapping finds a column's mapping, ignoring case
+func lookupMapping(mapping map[string]string, column string) (string, bool) {
+	for from, to := range mapping {
+		if strings.EqualFold(from, column) {
+			return to, true
+		}
+	}
+	return "", false
+}
+
+// fieldSlug turns a column header into a field slug, e.g. "Preferred Roles" into "preferred_roles"
+func fieldSlug(header string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(header)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteRune('_')
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+func checkUniqueIDs(players []Player) error {
+	seen := make(map[float64]string)
+	for _, player := range players {
+		if other, ok := seen[player.ID]; ok {
+			return fmt.Errorf("%v and %v have the same ID %v", other, player.Name, formatID(player.ID))
+		}
+		seen[player.ID] = player.Name
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// loadedPlayer is the part of a loaded player the source tests check, with answers written out as text
+type loadedPlayer struct {
+	ID       float64
+	Name     string
+	Pronouns string
+	Scene    string
+	Fields   map[string]string
+}
+
+// loadSource loads the source, returning its fields as slug and type pairs and its players as loadedPlayers
+func loadSource(t *testing.T, source PlayerSource) (fields [][2]string, players []loadedPlayer, err error) {
+	t.Helper()
+	formFields, loaded, err := source.Load(context.Background())
+	for _, field := range formFields {
+		fields = append(fields, [2]string{field.Slug, field.Type})
+	}
+	for _, player := range loaded {
+		answers := make(map[string]string)
+		for slug, value := range player.FormFields {
+			answers[slug] = value.String()
+		}
+		players = append(players, loadedPlayer{ID: player.ID, Name: player.Name, Pronouns: player.Pronouns, Scene: player.Scene, Fields: answers})
+	}
+	return fields, players, err
+}
+
+func TestCSVSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		mapping     map[string]string
+		wantFields  [][2]string
+		wantPlayers []loadedPlayer
+		wantErr     bool
+	}{
+		{
+			name:       "headers become fields",
+			data:       "Name,Pronouns,Skill,Preferred Roles\nAlice,she/her,3,\"Queen, Speed Warrior\"\n Bob ,,5,Queen\n",
+			wantFields: [][2]string{{"skill", numberField}, {"preferred_roles", textField}},
+			wantPlayers: []loadedPlayer{
+				{ID: 1, Name: "Alice", Pronouns: "she/her", Fields: map[string]string{"skill": "3", "preferred_roles": "Queen, Speed Warrior"}},
+				{ID: 2, Name: "Bob", Fields: map[string]string{"skill": "5", "preferred_roles": "Queen"}},
+			},
+		},
+		{
+			name:       "mapped columns",
+			data:       "Full Name,Home Scene,Level,Roles I Play,Notes\nAlice,kqpdx,3,\"Queen, Speed Warrior\",bring snacks\n",
+			mapping:    map[string]string{"full name": "name", "Home Scene": "scene", "LEVEL": "skill", "Roles I Play": "roles", "Notes": "-"},
+			wantFields: [][2]string{{"skill", numberField}, {"roles", multiselectField}},
+			wantPlayers: []loadedPlayer{
+				{ID: 1, Name: "Alice", Scene: "kqpdx", Fields: map[string]string{"skill": "3", "roles": "Queen, Speed Warrior"}},
+			},
+		},
+		{
+			name: "IDs from the sheet, or numbered by row",
+			data: "ID,Name\n7,Alice\n,Bob\nx,Cat\n",
+			wantPlayers: []loadedPlayer{
+				{ID: 7, Name: "Alice", Fields: map[string]string{}},
+				{ID: 2, Name: "Bob", Fields: map[string]string{}},
+				{ID: 3, Name: "Cat", Fields: map[string]string{}},
+			},
+		},
+		{
+			name:       "rows without a name are skipped",
+			data:       "Name,Skill\nAlice,1\n,2\nCat,3\n",
+			wantFields: [][2]string{{"skill", numberField}},
+			wantPlayers: []loadedPlayer{
+				{ID: 1, Name: "Alice", Fields: map[string]string{"skill": "1"}},
+				{ID: 3, Name: "Cat", Fields: map[string]string{"skill": "3"}},
+			},
+		},
+		{
+			name:       "two columns for the same field",
+			data:       "Name,Skill,skill\nAlice,1,2\n",
+			wantFields: [][2]string{{"skill", numberField}, {"skill_2", textField}},
+			wantPlayers: []loadedPlayer{
+				{ID: 1, Name: "Alice", Fields: map[string]string{"skill": "1", "skill_2": "2"}},
+			},
+		},
+		{
+			name:        "short rows",
+			data:        "Name,Pronouns,Skill\nAlice\n",
+			wantFields:  [][2]string{{"skill", numberField}},
+			wantPlayers: []loadedPlayer{{ID: 1, Name: "Alice", Fields: map[string]string{}}},
+		},
+		{name: "no name column", data: "Player,Skill\nAlice,1\n", wantErr: true},
+		{name: "name column skipped", data: "Name,Skill\nAlice,1\n", mapping: map[string]string{"Name": "-"}, wantErr: true},
+		{name: "header only", data: "Name,Skill\n", wantErr: true},
+		{name: "unreadable CSV", data: "Name\n\"Alice\n", wantErr: true},
+		{name: "duplicate IDs", data: "ID,Name\n2,Alice\n,Bob\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, players, err := loadSource(t, CSVSource{Data: []byte(tt.data), Mapping: tt.mapping})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("got fields %v, want %v", fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(players, tt.wantPlayers) {
+				t.Errorf("got players %+v, want %+v", players, tt.wantPlayers)
+			}
+		})
+	}
+}
+
+func TestJSONSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantFields  [][2]string
+		wantPlayers []loadedPlayer
+		wantErr     bool
+	}{
+		{
+			name: "HiveMind's shape",
+			data: `{
+				"formFields": [
+					{"field_name": "f_1a2b", "field_slug": "skill", "field_description": "Skill level"},
+					{"field_name": "f_3c4d", "field_slug": "sub", "field_description": "Happy to sub?", "field_type": "boolean"}
+				],
+				"players": [
+					{"id": 10, "name": "Alice", "pronouns": "she/her", "scene": "kqpdx", "f_1a2b": "4", "f_3c4d": true},
+					{"id": 11, "name": "Bob", "f_1a2b": 2}
+				]
+			}`,
+			wantFields: [][2]string{{"skill", numberField}, {"sub", checkboxField}},
+			wantPlayers: []loadedPlayer{
+				{ID: 10, Name: "Alice", Pronouns: "she/her", Scene: "kqpdx", Fields: map[string]string{"skill": "4", "sub": "Yes"}},
+				{ID: 11, Name: "Bob", Fields: map[string]string{"skill": "2"}},
+			},
+		},
+		{
+			name:       "a bare list with answers keyed by slug",
+			data:       `[{"name": "Alice", "form_fields": {"skill": 3, "altname": "Al"}}, {"name": "Bob", "form_fields": {"skill": 1}}]`,
+			wantFields: [][2]string{{"altname", textField}, {"skill", numberField}},
+			wantPlayers: []loadedPlayer{
+				{ID: 1, Name: "Alice", Fields: map[string]string{"skill": "3", "altname": "Al"}},
+				{ID: 2, Name: "Bob", Fields: map[string]string{"skill": "1"}},
+			},
+		},
+		{
+			name: "players without a name are skipped",
+			data: `{"players": [{"name": ""}, {"id": 4, "name": "Bob"}, {"name": "Cat"}]}`,
+			wantPlayers: []loadedPlayer{
+				{ID: 4, Name: "Bob", Fields: map[string]string{}},
+				{ID: 3, Name: "Cat", Fields: map[string]string{}},
+			},
+		},
+		{name: "no players", data: `{"players": []}`, wantErr: true},
+		{name: "only nameless players", data: `[{"id": 1}]`, wantErr: true},
+		{name: "unreadable JSON", data: `{"players": [`, wantErr: true},
+		{name: "duplicate IDs", data: `[{"id": 2, "name": "Alice"}, {"name": "Bob"}]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, players, err := loadSource(t, JSONSource{Data: []byte(tt.data)})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("got fields %v, want %v", fields, tt.wantFields)
+			}
+			if !reflect.DeepEqual(players, tt.wantPlayers) {
+				t.Errorf("got players %+v, want %+v", players, tt.wantPlayers)
+			}
+		})
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{filename: "players.json", data: "Name\nAlice\n", want: jsonSource},
+		{filename: "players.CSV", data: `[{"name": "Alice"}]`, want: csvSource},
+		{filename: "players.txt", data: ` {"players": []}`, want: jsonSource},
+		{filename: "players", data: `[{"name": "Alice"}]`, want: jsonSource},
+		{filename: "players.txt", data: "Name\nAlice\n", want: csvSource},
+		{filename: "", data: "", want: csvSource},
+	}
+	for _, tt := range tests {
+		if got := fileSource(tt.filename, []byte(tt.data), nil).Name(); got != tt.want {
+			t.Errorf("fileSource(%q, %q) is a %v source, want %v", tt.filename, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseColumnMapping(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{text: "", want: map[string]string{}},
+		{text: "Full Name = name", want: map[string]string{"Full Name": "name"}},
+		{text: "\n  Full Name=name \n\nNotes = -\nA = b = c\n", want: map[string]string{"Full Name": "name", "Notes": "-", "A": "b = c"}},
+		{text: "Full Name = name\nLevel", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseColumnMapping(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseColumnMapping(%q) got error %v, want error %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseColumnMapping(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFieldSlug(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "Skill", want: "skill"},
+		{header: "Preferred Roles", want: "preferred_roles"},
+		{header: "  What's your skill level (1-5)? ", want: "what_s_your_skill_level_1_5"},
+		{header: "--Email--", want: "email"},
+		{header: "Año", want: "año"},
+		{header: "???", want: ""},
+	}
+	for _, tt := range tests {
+		if got := fieldSlug(tt.header); got != tt.want {
+			t.Errorf("fieldSlug(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+// DraftState is the read-only snapshot of a draft served to spectator pages, stream overlays and outside tooling
+type DraftState struct {
+	DraftID        string          `json:"draftID"`
+	Scene          string          `json:"scene"`
+	Tournament     string          `json:"tournament"`
+	Stage          string          `json:"stage"`
+	Mode           string          `json:"mode"`
+	Format         string          `json:"format"`
+	Finished       bool            `json:"finished"`
+	CurrentCaptain string          `json:"currentCaptain"`
+	CurrentSlot    Slot            `json:"currentSlot"`
+	LatestPick     *Pick           `json:"latestPick"`
+	Clock          *ClockState     `json:"clock"`
+	Lot            *AuctionLot     `json:"lot,omitempty"`
+	DraftOrder     []Captain       `json:"draftOrder"`
+	Teams          []SpectatorTeam `json:"teams"`
+	Picks          []Pick          `json:"picks"`
+	Remaining      int             `json:"remaining"`
+	LastEventID    int             `json:"lastEventID"` // Pass to /events as lastEventID to pick up from this snapshot
+}
+
+// SpectatorTeam is a roster as spectators see it, with only the names players go by and none of their registration answers
+type SpectatorTeam struct {
+	ID      int
+	Name    string
+	Players []SpectatorPlayer `json:"players"`
+}
+
+// SpectatorPlayer is one player on a spectator roster
+type SpectatorPlayer struct {
+	Name    string `json:"name"`
+	AltName string `json:"altName,omitempty"`
+}
+
+// spectatorTeams strips the rosters down to what's safe to show anyone with the draft's link
+func spectatorTeams(teams []TeamInfo) []SpectatorTeam {
+	public := make([]SpectatorTeam, 0, len(teams))
+	for _, team := range teams {
+		players := make([]SpectatorPlayer, 0, len(team.Players))
+		for _, player := range team.Players {
+			players = append(players, SpectatorPlayer{Name: player.Name, AltName: player.AltName()})
+		}
+		public = append(public, SpectatorTeam{ID: team.ID, Name: team.Name, Players: players})
+	}
+	return public
+}
+
+// ClockState is the pick clock as spectators see it
+type ClockState struct {
+	Seconds int  `json:"seconds"`
+	Paused  bool `json:"paused"`
+}
+
+// State snapshots the draft for spectators. It only reads the draft, the rosters are the ones last fetched from HiveMind.
+func (d *Draft) State() DraftState {
+	state := DraftState{
+		DraftID:        d.ID,
+		Scene:          d.Branding().Scene,
+		Tournament:     d.TournamentName(),
+		Stage:          d.Stage(),
+		Mode:           d.Mode,
+		Format:         d.FormatLabel(),
+		Finished:       d.Finished(),
+		CurrentCaptain: d.peekCaptain().Name,
+		CurrentSlot:    d.peekSlot(),
+		DraftOrder:     d.DraftOrder,
+		Teams:          spectatorTeams(d.Teams),
+		Picks:          d.Picks,
+		Remaining:      len(d.DraftPlayers),
+		LastEventID:    d.lastEventID(),
+	}
+
+	if len(d.Picks) > 0 {
+		latest := d.Picks[len(d.Picks)-1]
+		state.LatestPick = &latest
+	}
+	if d.clockRunning() {
+		state.Clock = &ClockState{Seconds: int(d.ClockRemaining().Seconds()), Paused: d.Clock.Paused}
+	}
+	if d.Auction != nil {
+		state.Lot = d.Auction.Lot
+	}
+
+	return state
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// privateDraft is a draft two picks in whose players all answered private form fields
+func privateDraft(t *testing.T) *Draft {
+	t.Helper()
+
+	d := localDraft(t, 6, 2)
+	for _, list := range [][]Player{d.Players, d.DraftPlayers} {
+		for i := range list {
+			list[i].FormFields["email"] = StringValue("player@example.com")
+			list[i].FormFields["altname"] = StringValue("Alt")
+		}
+	}
+	for _, id := range []float64{3, 4} {
+		if _, err := d.PickPlayer(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+// assertNoFormFields fails the test if the JSON has anything from the players' form fields besides alt names
+func assertNoFormFields(t *testing.T, what string, data []byte) {
+	t.Helper()
+	for _, private := range []string{"form_fields", "player@example.com", "skill"} {
+		if strings.Contains(string(data), private) {
+			t.Errorf("%v has %q in it: %s", what, private, data)
+		}
+	}
+	if !strings.Contains(string(data), `"altName":"Alt"`) {
+		t.Errorf("%v is missing the players' alt names: %s", what, data)
+	}
+}
+
+func TestStateHidesFormFields(t *testing.T) {
+	d := privateDraft(t)
+
+	state := d.State()
+	players := 0
+	for _, team := range state.Teams {
+		players += len(team.Players)
+	}
+	if players != 4 {
+		t.Fatalf("the state's teams have %v players, want the 2 captains and 2 picks", players)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoFormFields(t, "the state", data)
+}
+
+func TestPublishTeamsHidesFormFields(t *testing.T) {
+	d := privateDraft(t)
+	_, ch, _ := d.events.Subscribe(d.events.LastID())
+	defer d.events.Unsubscribe(ch)
+
+	d.publishTeams()
+	event := <-ch
+	if event.Type != "teams" {
+		t.Fatalf("got a %v event, want teams", event.Type)
+	}
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoFormFields(t, "the teams event", data)
+}
+
+func TestStateJSONRoute(t *testing.T) {
+	startFakeHiveMind(t)
+	d := privateDraft(t)
+	drafts.drafts[d.ID] = d
+
+	w := httptest.NewRecorder()
+	setupRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/drafts/"+d.ID+"/state.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", w.Code, http.StatusOK)
+	}
+	assertNoFormFields(t, "/state.json", w.Body.Bytes())
+}
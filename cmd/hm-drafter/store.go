@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/imandradesign/hm-drafter/kvstore"
+)
+
+// DraftStore saves draft sessions somewhere that outlives the process, so a dyno restart doesn't lose a draft in progress. It also keeps the pair requests for each tournament and the ratings for each scene, which outlive any one draft.
+type DraftStore interface {
+	Save(d *Draft) error
+	LoadAll() ([]*Draft, error)
+	Delete(id string) error
+	PairStore
+	RatingStore
+}
+
+// PairStore keeps each tournament's pair requests
+type PairStore interface {
+	LoadPairs(tournament string) ([]PairRequest, error)
+	SavePairs(tournament string, requests []PairRequest) error
+}
+
+// RatingStore keeps each scene's player ratings, so they only need fetching from HiveMind once
+type RatingStore interface {
+	LoadRatings(scene string) (*Ratings, error)
+	SaveRatings(ratings *Ratings) error
+}
+
+// OpenDraftStore opens a store from a "backend:path" spec, e.g. "file:data/drafts" or "kv:data/drafts.db". An empty spec or "memory" keeps drafts in memory only.
+func OpenDraftStore(spec string) (DraftStore, error) {
+	if spec == "" || spec == "memory" {
+		return newMemoryStore(), nil
+	}
+
+	backend, path, found := strings.Cut(spec, ":")
+	if !found || path == "" {
+		return nil, fmt.Errorf("invalid store %q, expected backend:path", spec)
+	}
+
+	switch backend {
+	case "file":
+		return NewFileStore(path)
+	case "kv":
+		return NewKVStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", backend)
+	}
+}
+
+// decodeDraft reads a saved draft, bringing anything saved by an older version up to date
+func decodeDraft(data []byte) (*Draft, error) {
+	var d Draft
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+	d.retypeFieldValues()
+	return &d, nil
+}
+
+// memoryStore doesn't persist drafts, which the registry already keeps in memory. Pair requests and ratings are kept until the process exits.
+type memoryStore struct {
+	mu      sync.Mutex
+	pairs   map[string][]PairRequest
+	ratings map[string]*Ratings
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{pairs: make(map[string][]PairRequest), ratings: make(map[string]*Ratings)}
+}
+
+func (*memoryStore) Save(*Draft) error          { return nil }
+func (*memoryStore) LoadAll() ([]*Draft, error) { return nil, nil }
+func (*memoryStore) Delete(string) error        { return nil }
+
+func (s *memoryStore) LoadPairs(tournament string) ([]PairRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pairs[tournament], nil
+}
+
+func (s *memoryStore) SavePairs(tournament string, requests []PairRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pairs[tournament] = requests
+	return nil
+}
+
+func (s *memoryStore) LoadRatings(scene string) (*Ratings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ratings[scene], nil
+}
+
+func (s *memoryStore) SaveRatings(ratings *Ratings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ratings[ratings.Scene] = ratings
+	return nil
+}
+
+// FileStore keeps each draft in its own JSON file in a directory
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Save writes the draft to its file
+func (s *FileStore) Save(d *Draft) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(d.ID), data)
+}
+
+// writeFileAtomic writes to a temp file and renames it into place so a crash mid-write never leaves a half-written file behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileStore) LoadAll() (loaded []*Draft, err error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := decodeDraft(data)
+		if err != nil {
+			log.Printf("Skipping unreadable draft file %v: %v", file, err)
+			continue
+		}
+		loaded = append(loaded, d)
+	}
+
+	return loaded, nil
+}
+
+func (s *FileStore) Delete(id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// pairsPath is the file for a tournament's pair requests, in a subdirectory so LoadAll doesn't mistake it for a draft
+func (s *FileStore) pairsPath(tournament string) string {
+	return filepath.Join(s.dir, "pairs", tournament+".json")
+}
+
+func (s *FileStore) LoadPairs(tournament string) (requests []PairRequest, err error) {
+	data, err := os.ReadFile(s.pairsPath(tournament))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &requests)
+	return requests, err
+}
+
+func (s *FileStore) SavePairs(tournament string, requests []PairRequest) error {
+	data, err := json.MarshalIndent(requests, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.pairsPath(tournament)), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.pairsPath(tournament), data)
+}
+
+// ratingsPath is the file for a scene's ratings, in a subdirectory like the pair requests
+func (s *FileStore) ratingsPath(scene string) string {
+	return filepath.Join(s.dir, "ratings", scene+".json")
+}
+
+func (s *FileStore) LoadRatings(scene string) (*Ratings, error) {
+	data, err := os.ReadFile(s.ratingsPath(scene))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var ratings Ratings
+	if err := json.Unmarshal(data, &ratings); err != nil {
+		return nil, err
+	}
+	return &ratings, nil
+}
+
+func (s *FileStore) SaveRatings(ratings *Ratings) error {
+	data, err := json.MarshalIndent(ratings, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.ratingsPath(ratings.Scene)), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.ratingsPath(ratings.Scene), data)
+}
+
+// KVStore keeps drafts in an embedded key-value database file
+type KVStore struct {
+	db *kvstore.DB
+}
+
+const (
+	kvDraftPrefix   = "draft/"
+	kvPairsPrefix   = "pairs/"
+	kvRatingsPrefix = "ratings/"
+)
+
+func NewKVStore(path string) (*KVStore, error) {
+	db, err := kvstore.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &KVStore{db: db}, nil
+}
+
+func (s *KVStore) Save(d *Draft) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(kvDraftPrefix+d.ID, data)
+}
+
+func (s *KVStore) LoadAll() (loaded []*Draft, err error) {
+	for _, key := range s.db.Keys(kvDraftPrefix) {
+		data, _ := s.db.Get(key)
+
+		d, err := decodeDraft(data)
+		if err != nil {
+			log.Printf("Skipping unreadable draft %v: %v", key, err)
+			continue
+		}
+		loaded = append(loaded, d)
+	}
+
+	return loaded, nil
+}
+
+func (s *KVStore) Delete(id string) error {
+	return s.db.Delete(kvDraftPrefix + id)
+}
+
+func (s *KVStore) LoadPairs(tournament string) (requests []PairRequest, err error) {
+	data, ok := s.db.Get(kvPairsPrefix + tournament)
+	if !ok {
+		return nil, nil
+	}
+	err = json.Unmarshal(data, &requests)
+	return requests, err
+}
+
+func (s *KVStore) SavePairs(tournament string, requests []PairRequest) error {
+	data, err := json.Marshal(requests)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(kvPairsPrefix+tournament, data)
+}
+
+func (s *KVStore) LoadRatings(scene string) (*Ratings, error) {
+	data, ok := s.db.Get(kvRatingsPrefix + scene)
+	if !ok {
+		return nil, nil
+	}
+	var ratings Ratings
+	if err := json.Unmarshal(data, &ratings); err != nil {
+		return nil, err
+	}
+	return &ratings, nil
+}
+
+func (s *KVStore) SaveRatings(ratings *Ratings) error {
+	data, err := json.Marshal(ratings)
+	if err != nil {
+		return err
+	}
+	return s.db.Put(kvRatingsPrefix+ratings.Scene, data)
+}
@@ -1,15 +1,5 @@
 package main
 
-type FormFields struct {
-	FieldName        string    `json:"field_name"`
-	FieldSlug      string `json:"field_slug"`
-	FieldDescription      string `json:"field_description"`
-}
-
-type FormApiResponse struct {
-	Results []FormFields `json:"results"`
-}
-
 type Player struct {
 	Name       string            `json:"name"`
 	ID         float64           `json:"id"`
@@ -18,11 +8,7 @@ type Player struct {
 	Tournament int               `json:"tournament"`
 	Team       int               `json:"team"`
 	Image      string            `json:"image"`
-	FormFields map[string]string `json:"form_fields,omitempty"`
-}
-
-type PlayersApiResponse struct {
-	Results []map[string]interface{} `json:"results"`
+	FormFields map[string]FieldValue `json:"form_fields,omitempty"`
 }
 
 type Captain struct {
@@ -30,12 +16,7 @@ type Captain struct {
 	Name  string
 	AltName string
 	Order int
-}
-
-type Team struct {
-	ID         int    `json:"id"`
-	Name       string `json:"name"`
-	Tournament int    `json:"tournament"`
+	TeamID int // HiveMind team the captain was assigned to, 0 until assigned
 }
 
 type TeamInfo struct {
@@ -43,22 +24,3 @@ type TeamInfo struct {
 	Name    string
 	Players []Player `json:"players,omitempty"`
 }
-
-type TeamApiResponse struct {
-	Results []Team `json:"results"`
-}
-
-type UpdatePlayerTeamRequest struct {
-    Team int `json:"team"`
-}
-
-type Tournament struct {
-	ID        int    `json:"id"`
-	Name      string `json:"name"`
-	Date      string `json:"date"`
-	SceneName string `json:"scene_name"`
-}
-
-type TourneyAPIResponse struct {
-	Results []Tournament `json:"results"`
-}
\ No newline at end of file
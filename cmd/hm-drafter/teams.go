@@ -1,38 +1,34 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"strconv"
 )
 
-func GetTeams(tournamentID string, players []Player) (teams []TeamInfo) {
+func GetTeams(ctx context.Context, tournamentID string, players []Player) (teams []TeamInfo, err error) {
 	log.Printf("Starting GetTeams Func. Tournament ID passed in: %v", tournamentID)
 
+	id, err := parseID(tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tournament ID %q: %w", tournamentID, err)
+	}
+
 	// Fetch Teams from the API
-	api := fmt.Sprintf("https://kqhivemind.com/api/tournament/team/?tournament_id=%v&format=json", tournamentID)
-	client, req := createRequest("GET", api, nil)
-	resp, err := client.Do(req)
+	results, err := hm.Teams(ctx, id)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("fetching teams: %w", err)
 	}
-	defer resp.Body.Close()
 
-	var teamApiResponse TeamApiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&teamApiResponse); err != nil {
-		log.Fatal(err)
+	for _, team := range results {
+		teams = append(teams, TeamInfo{ID: team.ID, Name: team.Name, Players: []Player{}})
 	}
 
 	// Create a map of team IDs to TeamInfo pointers for quick access
 	teamMap := make(map[int]*TeamInfo)
-	for _, team := range teamApiResponse.Results {
-		teamInfo := TeamInfo{ID: team.ID, Name: team.Name, Players: []Player{}}
-		teams = append(teams, teamInfo)
-		teamMap[team.ID] = &teams[len(teams)-1] // Map each TeamInfo by its ID
+	for i := range teams {
+		teamMap[teams[i].ID] = &teams[i] // Map each TeamInfo by its ID
 	}
 
 	// Iterate over players and add them to the matching team in teamMap
@@ -43,112 +39,55 @@ func GetTeams(tournamentID string, players []Player) (teams []TeamInfo) {
 	}
 
 	log.Printf("TEAMS with Players:\n%v", teams)
-	return teams
+	return teams, nil
 }
 
 
-func AddNewTeam(teamName string, tournamentID string) {
+func AddNewTeam(ctx context.Context, teamName string, tournamentID string) (teamID int, err error) {
 	// Convert tournament ID to an integer
-	tournamentIDInt, err := strconv.Atoi(tournamentID)
-	if err != nil {
-		log.Print("Unable to convert tournament ID string to int in AddTeam() func.")
-	}
-
-	// Create the team struct to send to the API
-	newTeam := Team{
-		Name:       teamName,
-		Tournament: tournamentIDInt,
-	}
-
-	// Convert the team struct to JSON
-	teamJSON, err := json.Marshal(newTeam)
+	tournamentIDInt, err := parseID(tournamentID)
 	if err != nil {
-		log.Fatalf("error marshalling team data: %v", err)
+		return 0, fmt.Errorf("invalid tournament ID %q: %w", tournamentID, err)
 	}
 
-	api := fmt.Sprintf("https://kqhivemind.com/api/tournament/team/?tournament_id=%v&format=json", tournamentID)
-
-	client, req := createRequest("POST", api, bytes.NewBuffer(teamJSON))
-	req.Header.Set("Content-Type", "application/json")
-
-	// Make the POST request
-	resp, err := client.Do(req)
+	team, err := hm.CreateTeam(ctx, tournamentIDInt, teamName)
 	if err != nil {
-		log.Fatalf("error making POST request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check the response status code
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		log.Fatalf("Failed to add team. Status: %v, Response: %s", resp.Status, string(body))
+		return 0, fmt.Errorf("failed to add team %q: %w", teamName, err)
 	}
 
-	log.Printf("Request to add team returned status: %v", resp.Status)
+	log.Printf("Added team %v (ID: %v)", team.Name, team.ID)
+	return team.ID, nil
 }
 
 
-func DeleteTeam(teamID string, teamName string, tournamentID string) (playerIDs []string) {
-	// Construct the API URL to retrieve team information
-	apiGet := fmt.Sprintf("https://kqhivemind.com/api/tournament/team/%s/?tournament_id=%v", teamID, tournamentID)
-
-	// Create a GET request to retrieve the team information
-	client, reqGet := createRequest("GET", apiGet, nil)
-	reqGet.Header.Set("Content-Type", "application/json")
-
-	// Make the GET request to fetch team data
-	respGet, err := client.Do(reqGet)
+func DeleteTeam(ctx context.Context, teamID string, teamName string, tournamentID string) (playerIDs []string, err error) {
+	teamIDInt, err := parseID(teamID)
 	if err != nil {
-		log.Fatalf("error making team GET request: %v", err)
+		return nil, fmt.Errorf("invalid team ID %q: %w", teamID, err)
 	}
-	defer respGet.Body.Close()
-
-	// Check if GET request was successful
-	if respGet.StatusCode == http.StatusOK {
-		var teamData struct {
-			Players []struct {
-				ID string `json:"id"`
-			} `json:"players"`
-		}
-
-		// Parse the JSON response to get the player IDs
-		err = json.NewDecoder(respGet.Body).Decode(&teamData)
-		if err != nil {
-			log.Fatalf("error decoding team data: %v", err)
-		}
 
+	// Retrieve the team so we know which players were on it
+	team, err := hm.Team(ctx, teamIDInt)
+	if err != nil {
+		log.Printf("Failed to fetch team details: %v", err)
+	} else {
 		// Collect all player IDs from the team
-		for _, player := range teamData.Players {
-			playerIDs = append(playerIDs, player.ID)
+		for _, player := range team.Players {
+			playerIDs = append(playerIDs, strconv.Itoa(player.ID))
 		}
 
 		log.Printf("Players in team %s (ID: %s): %v", teamName, teamID, playerIDs)
-	} else {
-		log.Printf("Failed to fetch team details. Status: %v", respGet.Status)
 	}
 
-	// Now, construct the API URL for the DELETE request
-	apiDelete := fmt.Sprintf("https://kqhivemind.com/api/tournament/team/%s/?tournament_id=%v", teamID, tournamentID)
-	client, reqDelete := createRequest("DELETE", apiDelete, nil)
-	reqDelete.Header.Set("Content-Type", "application/json")
-
-	// Make the DELETE request to delete the team
-	respDelete, err := client.Do(reqDelete)
-	if err != nil {
-		log.Fatalf("error making team DELETE request: %v", err)
+	// Now delete the team
+	if err := hm.DeleteTeam(ctx, teamIDInt); err != nil {
+		return nil, fmt.Errorf("failed to delete team %q: %w", teamName, err)
 	}
-	defer respDelete.Body.Close()
 
-	// Check the response status code for DELETE
-	if respDelete.StatusCode != http.StatusOK && respDelete.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(respDelete.Body)
-		log.Fatalf("Failed to delete team. Status: %v, Response: %s", respDelete.Status, string(body))
-	} else {
-		log.Printf("Team ID %s deleted successfully with status: %v", teamID, respDelete.Status)
-	}
+	log.Printf("Team ID %s deleted successfully (tournament %v)", teamID, tournamentID)
 
 	// Return the list of player IDs that were in the team before deletion
-	return playerIDs
+	return playerIDs, nil
 }
 
 
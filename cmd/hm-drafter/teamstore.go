@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// TeamStore is where a draft's teams live. Drafts loaded from HiveMind write teams back to HiveMind, imported drafts keep them in the draft itself.
+type TeamStore interface {
+	// Teams lists the teams with their players filled in from players
+	Teams(ctx context.Context, players []Player) ([]TeamInfo, error)
+	// AddTeam creates a team and returns its ID
+	AddTeam(ctx context.Context, name string) (int, error)
+	// DeleteTeam removes a team and returns the IDs of the players who were on it
+	DeleteTeam(ctx context.Context, teamID, teamName string) ([]string, error)
+	// AssignPlayer moves a player onto a team, or off their team if teamID is ""
+	AssignPlayer(ctx context.Context, playerID, teamID string) error
+}
+
+// teamStore returns the store for the draft's teams
+func (d *Draft) teamStore() TeamStore {
+	if d.Source == "" || d.Source == hivemindSource {
+		return HiveMindTeams{TournamentID: d.TournamentID}
+	}
+	return localTeams{d: d}
+}
+
+// HiveMindTeams keeps teams in a HiveMind tournament
+type HiveMindTeams struct {
+	TournamentID string
+}
+
+func (s HiveMindTeams) Teams(ctx context.Context, players []Player) ([]TeamInfo, error) {
+	return GetTeams(ctx, s.TournamentID, players)
+}
+
+func (s HiveMindTeams) AddTeam(ctx context.Context, name string) (int, error) {
+	return AddNewTeam(ctx, name, s.TournamentID)
+}
+
+func (s HiveMindTeams) DeleteTeam(ctx context.Context, teamID, teamName string) ([]string, error) {
+	return DeleteTeam(ctx, teamID, teamName, s.TournamentID)
+}
+
+func (s HiveMindTeams) AssignPlayer(ctx context.Context, playerID, teamID string) error {
+	return AssignPlayerToTeam(ctx, playerID, teamID, s.TournamentID)
+}
+
+// localTeams keeps teams in the draft, which the draft store saves along with everything else
+type localTeams struct {
+	d *Draft
+}
+
+func (s localTeams) Teams(ctx context.Context, players []Player) ([]TeamInfo, error) {
+	var teams []TeamInfo
+	for _, team := range s.d.LocalTeams {
+		info := TeamInfo{ID: team.ID, Name: team.Name, Players: []Player{}}
+		for _, player := range players {
+			if player.Team == team.ID {
+				info.Players = append(info.Players, player)
+			}
+		}
+		teams = append(teams, info)
+	}
+	return teams, nil
+}
+
+func (s localTeams) AddTeam(ctx context.Context, name string) (int, error) {
+	if name == "" {
+		return 0, fmt.Errorf("teams need a name")
+	}
+
+	id := 1
+	for _, team := range s.d.LocalTeams {
+		if team.ID >= id {
+			id = team.ID + 1
+		}
+	}
+	s.d.LocalTeams = append(s.d.LocalTeams, TeamInfo{ID: id, Name: name})
+
+	log.Printf("Draft %v: added local team %v (ID: %v)", s.d.ID, name, id)
+	return id, nil
+}
+
+func (s localTeams) DeleteTeam(ctx context.Context, teamID, teamName string) (playerIDs []string, err error) {
+	id, err := parseID(teamID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid team ID %q: %w", teamID, err)
+	}
+
+	var remaining []TeamInfo
+	for _, team := range s.d.LocalTeams {
+		if team.ID != id {
+			remaining = append(remaining, team)
+		}
+	}
+	if len(remaining) == len(s.d.LocalTeams) {
+		return nil, fmt.Errorf("team %q not found", teamName)
+	}
+	s.d.LocalTeams = remaining
+
+	for _, player := range s.d.Players {
+		if player.Team == id {
+			playerIDs = append(playerIDs, formatID(player.ID))
+		}
+	}
+	return playerIDs, nil
+}
+
+func (s localTeams) AssignPlayer(ctx context.Context, playerID, teamID string) error {
+	id, err := parseID(playerID)
+	if err != nil {
+		return fmt.Errorf("invalid player ID %q: %w", playerID, err)
+	}
+
+	team := 0
+	if teamID != "" && teamID != "0" {
+		if team, err = parseID(teamID); err != nil {
+			return fmt.Errorf("invalid team ID %q: %w", teamID, err)
+		}
+		if !s.hasTeam(team) {
+			return fmt.Errorf("team %v not found", teamID)
+		}
+	}
+
+	s.d.setPlayerTeam(float64(id), team)
+	return nil
+}
+
+func (s localTeams) hasTeam(id int) bool {
+	for _, team := range s.d.LocalTeams {
+		if team.ID == id {
+			return true
+		}
+	}
+	return false
+}
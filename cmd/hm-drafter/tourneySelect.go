@@ -1,57 +1,214 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind"
 )
 
-// GetPDXTournies takes the API string that lists all tournaments, checks the `results` list for entries where the `scene_name` is `kqpdx` and returns those events with their ID, name, and date in nested lists
-func GetPDXTournies() (portlandTournies [][]string) {
-	log.Println("Fetching tournament data...")
+const (
+	// tournamentLimit is how many of a scene's tournaments the lobby offers
+	tournamentLimit = 10
 
-	listLen := len(portlandTournies)
-	page := 1
+	// tournamentPageSize is how many tournaments the browser shows per page
+	tournamentPageSize = 20
 
-	for listLen < 20 {
-		// Construct API URL with the tournament slug and page number
-		api := fmt.Sprintf(apiTemplate, "tournament", fmt.Sprintf("&page=%d", page), "")
-		
-		client, req := createRequest("GET", api, nil)
+	// tournamentCacheTTL is how long the fetched tournament list is reused, so paging through the browser doesn't walk every HiveMind page each time
+	tournamentCacheTTL = 2 * time.Minute
+)
 
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Fatal(err)
+var tournamentList tournamentDirectory
+
+var errInvalidTournamentID = errors.New("invalid tournament ID")
+
+// TournamentFilter narrows down the tournament browser. Dates are YYYY-MM-DD and either end of the range can be left blank.
+type TournamentFilter struct {
+	Scene string
+	Query string // Matches anywhere in the name, ignoring case
+	When  string // "upcoming", "past" or "" for both
+	From  string
+	To    string
+	Page  int // 1-based
+}
+
+// TournamentResults is one page of the tournament browser
+type TournamentResults struct {
+	Tournaments []hivemind.Tournament
+	Total       int
+	Page        int
+	Pages       int
+}
+
+// tournamentDirectory caches HiveMind's tournament list for a couple of minutes. Pages are fetched only as far as a caller needs, so the lobby doesn't walk every scene's tournaments.
+type tournamentDirectory struct {
+	mu       sync.Mutex
+	fetched  []hivemind.Tournament // Every page fetched so far, in HiveMind's order
+	nextPage int                   // The next page to fetch, 0 once there are no more
+	started  time.Time             // When the cache was started, so a stale one is thrown away
+}
+
+// load fetches pages until enough is satisfied with what's been fetched or HiveMind runs out of pages. The lock is only held to read and update the cache, never during a request to HiveMind.
+func (t *tournamentDirectory) load(ctx context.Context, enough func([]hivemind.Tournament) bool) ([]hivemind.Tournament, error) {
+	for {
+		t.mu.Lock()
+		if t.started.IsZero() || time.Since(t.started) >= tournamentCacheTTL {
+			t.fetched, t.nextPage, t.started = nil, 1, time.Now()
 		}
+		fetched, page, started := t.fetched, t.nextPage, t.started
+		t.mu.Unlock()
 
-		// Defer the closing of the body
-		defer resp.Body.Close()
+		if page == 0 || enough(fetched) {
+			return fetched, nil
+		}
 
-		// Decode the response into APIResponse struct
-		var apiResponse TourneyAPIResponse
-		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-			log.Fatal(err)
+		if page == 1 {
+			log.Println("Fetching tournament data...")
+		}
+		resp, err := hm.Tournaments(ctx, page)
+		if err != nil {
+			return nil, fmt.Errorf("fetching tournaments page %d: %w", page, err)
 		}
 
-		// Loop through each item in `results` and filter by `scene_name == "kqpdx"`
-		for _, tournament := range apiResponse.Results {
-			if tournament.SceneName == scene {
-				portlandTournies = append(portlandTournies, []string{
-					fmt.Sprintf("%d", tournament.ID), tournament.Name, tournament.Date,
-				})
+		t.mu.Lock()
+		// Someone else may have fetched this page, or started a fresh cache, while this request was out
+		if t.started.Equal(started) && t.nextPage == page {
+			// Capping the capacity makes append copy, so lists already handed out never change underneath their callers
+			t.fetched = append(t.fetched[:len(t.fetched):len(t.fetched)], resp.Results...)
+			t.nextPage = page + 1
+			if resp.Next == "" || len(resp.Results) == 0 {
+				t.nextPage = 0
+				log.Printf("API data fetched, %v tournaments.", len(t.fetched))
 			}
+		}
+		t.mu.Unlock()
+	}
+}
+
+// All returns every HiveMind tournament, walking the rest of the API's pages if the cache doesn't have them yet
+func (t *tournamentDirectory) All(ctx context.Context) ([]hivemind.Tournament, error) {
+	return t.load(ctx, func([]hivemind.Tournament) bool { return false })
+}
 
-			listLen = len(portlandTournies)
-			if listLen == 10 {
-				log.Println("API data fetched.")
-				return portlandTournies
+// Scene returns the tournaments fetched once at least want of them are the scene's, or every tournament if the scene doesn't have that many
+func (t *tournamentDirectory) Scene(ctx context.Context, scene string, want int) ([]hivemind.Tournament, error) {
+	return t.load(ctx, func(fetched []hivemind.Tournament) bool {
+		count := 0
+		for _, tournament := range fetched {
+			if strings.EqualFold(tournament.SceneName, scene) {
+				count++
 			}
 		}
+		return count >= want
+	})
+}
+
+// SearchTournaments returns the page of tournaments matching the filter. Upcoming tournaments are listed soonest first, everything else newest first.
+func SearchTournaments(ctx context.Context, filter TournamentFilter) (results TournamentResults, err error) {
+	all, err := tournamentList.All(ctx)
+	if err != nil {
+		return results, err
+	}
+	return filterTournaments(all, filter), nil
+}
+
+// filterTournaments picks out the page of tournaments matching the filter
+func filterTournaments(all []hivemind.Tournament, filter TournamentFilter) (results TournamentResults) {
+	today := time.Now().Format("2006-01-02")
+	query := strings.ToLower(strings.TrimSpace(filter.Query))
+
+	var matches []hivemind.Tournament
+	for _, tournament := range all {
+		date := tournamentDate(tournament)
+		switch {
+		case filter.Scene != "" && !strings.EqualFold(tournament.SceneName, filter.Scene):
+		case query != "" && !strings.Contains(strings.ToLower(tournament.Name), query):
+		case filter.When == "upcoming" && date < today:
+		case filter.When == "past" && date >= today:
+		case filter.From != "" && date < filter.From:
+		case filter.To != "" && date > filter.To:
+		default:
+			matches = append(matches, tournament)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if filter.When == "upcoming" {
+			return tournamentDate(matches[i]) < tournamentDate(matches[j])
+		}
+		return tournamentDate(matches[i]) > tournamentDate(matches[j])
+	})
+
+	results.Total = len(matches)
+	results.Pages = (len(matches) + tournamentPageSize - 1) / tournamentPageSize
+	results.Page = filter.Page
+	if results.Page < 1 {
+		results.Page = 1
+	}
+
+	start := (results.Page - 1) * tournamentPageSize
+	if start < len(matches) {
+		end := start + tournamentPageSize
+		if end > len(matches) {
+			end = len(matches)
+		}
+		results.Tournaments = matches[start:end]
+	}
+	return results
+}
+
+// GetSceneTournies returns the scene's most recent tournaments for the lobby. HiveMind lists the newest first, so it only fetches pages until the scene has enough.
+func GetSceneTournies(ctx context.Context, scene string) ([]hivemind.Tournament, error) {
+	fetched, err := tournamentList.Scene(ctx, scene, tournamentLimit)
+	if err != nil {
+		return nil, err
+	}
+	results := filterTournaments(fetched, TournamentFilter{Scene: scene})
+	if len(results.Tournaments) > tournamentLimit {
+		return results.Tournaments[:tournamentLimit], nil
+	}
+	return results.Tournaments, nil
+}
+
+// GetTournament fetches one tournament by ID, straight from HiveMind so a deep link never depends on the cached list
+func GetTournament(ctx context.Context, tournamentID string) (*hivemind.Tournament, error) {
+	id, err := strconv.Atoi(tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q", errInvalidTournamentID, tournamentID)
+	}
+
+	tournament, err := hm.Tournament(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching tournament %v: %w", id, err)
+	}
+	return tournament, nil
+}
+
+// tournamentErrorStatus is the status to show for a GetTournament error: not found for bad or unknown IDs, bad gateway when HiveMind is the problem
+func tournamentErrorStatus(err error) int {
+	if errors.Is(err, errInvalidTournamentID) || hivemind.IsNotFound(err) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadGateway
+}
 
-		page++
-		listLen = len(portlandTournies)
+// tournamentDate trims a tournament's date down to YYYY-MM-DD, so it sorts and compares as a string
+func tournamentDate(tournament hivemind.Tournament) string {
+	if len(tournament.Date) > 10 {
+		return tournament.Date[:10]
 	}
+	return tournament.Date
+}
 
-	log.Println("API data fetched.")
-	return portlandTournies
-}
\ No newline at end of file
+// selectedTournament is the [ID, name, date] a draft keeps for its tournament
+func selectedTournament(tournament hivemind.Tournament) []string {
+	return []string{strconv.Itoa(tournament.ID), tournament.Name, tournament.Date}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/imandradesign/hm-drafter/hivemind"
+	"github.com/imandradesign/hm-drafter/hivemind/hivemindtest"
+)
+
+// day is the date n days from today, as HiveMind writes it
+func day(n int) string {
+	return time.Now().AddDate(0, 0, n).Format("2006-01-02")
+}
+
+// tournamentIDs lists the IDs of tournaments in order
+func tournamentIDs(tournaments []hivemind.Tournament) (ids []int) {
+	for _, tournament := range tournaments {
+		ids = append(ids, tournament.ID)
+	}
+	return ids
+}
+
+func TestFilterTournaments(t *testing.T) {
+	all := []hivemind.Tournament{
+		{ID: 1, Name: "PDX Mixer", SceneName: "kqpdx", Date: day(-14)},
+		{ID: 2, Name: "SEA Mixer", SceneName: "kqsea", Date: day(-7)},
+		{ID: 3, Name: "PDX Draft Night", SceneName: "kqpdx", Date: day(-1)},
+		{ID: 4, Name: "PDX Mixer", SceneName: "kqpdx", Date: day(0) + "T19:00:00Z"},
+		{ID: 5, Name: "PDX Championship", SceneName: "kqpdx", Date: day(7)},
+		{ID: 6, Name: "SEA Draft Night", SceneName: "kqsea", Date: day(-7)},
+	}
+
+	tests := []struct {
+		name   string
+		filter TournamentFilter
+		want   []int
+	}{
+		{name: "everything, newest first", want: []int{5, 4, 3, 2, 6, 1}},
+		{name: "one scene, ignoring case", filter: TournamentFilter{Scene: "KQPDX"}, want: []int{5, 4, 3, 1}},
+		{name: "name search", filter: TournamentFilter{Query: " draft NIGHT "}, want: []int{3, 6}},
+		{name: "upcoming, soonest first and including today", filter: TournamentFilter{When: "upcoming"}, want: []int{4, 5}},
+		{name: "past", filter: TournamentFilter{When: "past"}, want: []int{3, 2, 6, 1}},
+		{name: "from a date", filter: TournamentFilter{From: day(-7)}, want: []int{5, 4, 3, 2, 6}},
+		{name: "up to a date", filter: TournamentFilter{To: day(-7)}, want: []int{2, 6, 1}},
+		{name: "between dates", filter: TournamentFilter{From: day(-8), To: day(0)}, want: []int{4, 3, 2, 6}},
+		{name: "every filter at once", filter: TournamentFilter{Scene: "kqpdx", Query: "mixer", When: "past", From: day(-30)}, want: []int{1}},
+		{name: "nothing matches", filter: TournamentFilter{Scene: "kqchi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := filterTournaments(all, tt.filter)
+			if got := tournamentIDs(results.Tournaments); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got tournaments %v, want %v", got, tt.want)
+			}
+			if results.Total != len(tt.want) {
+				t.Errorf("got a total of %v, want %v", results.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestFilterTournamentsPaging(t *testing.T) {
+	// 45 tournaments, one a day going back from today, so newest first is ID order
+	var all []hivemind.Tournament
+	for i := 1; i <= 45; i++ {
+		all = append(all, hivemind.Tournament{ID: i, Name: fmt.Sprintf("Mixer %d", i), Date: day(-i)})
+	}
+
+	tests := []struct {
+		page      int
+		wantPage  int
+		wantFirst int
+		wantLen   int
+	}{
+		{page: 0, wantPage: 1, wantFirst: 1, wantLen: tournamentPageSize},
+		{page: 1, wantPage: 1, wantFirst: 1, wantLen: tournamentPageSize},
+		{page: 2, wantPage: 2, wantFirst: tournamentPageSize + 1, wantLen: tournamentPageSize},
+		{page: 3, wantPage: 3, wantFirst: 2*tournamentPageSize + 1, wantLen: 45 - 2*tournamentPageSize},
+		{page: 4, wantPage: 4},
+		{page: -2, wantPage: 1, wantFirst: 1, wantLen: tournamentPageSize},
+	}
+
+	for _, tt := range tests {
+		results := filterTournaments(all, TournamentFilter{Page: tt.page})
+		if results.Page != tt.wantPage || results.Pages != 3 || results.Total != 45 {
+			t.Errorf("page %v: got page %v of %v with %v in total, want page %v of 3 with 45", tt.page, results.Page, results.Pages, results.Total, tt.wantPage)
+		}
+		if len(results.Tournaments) != tt.wantLen {
+			t.Fatalf("page %v: got %v tournaments, want %v", tt.page, len(results.Tournaments), tt.wantLen)
+		}
+		if tt.wantLen > 0 && results.Tournaments[0].ID != tt.wantFirst {
+			t.Errorf("page %v starts with tournament %v, want %v", tt.page, results.Tournaments[0].ID, tt.wantFirst)
+		}
+	}
+}
+
+func TestTournamentDirectory(t *testing.T) {
+	// 25 tournaments over 3 of the fake's pages, newest first like HiveMind. Every fifth is Seattle's.
+	fx := &hivemindtest.Fixtures{}
+	for i := 1; i <= 25; i++ {
+		scene := "kqpdx"
+		if i%5 == 0 {
+			scene = "kqsea"
+		}
+		fx.Tournaments = append(fx.Tournaments, map[string]interface{}{
+			"id": i, "name": fmt.Sprintf("Mixer %d", i), "date": day(-i), "scene_name": scene,
+		})
+	}
+
+	tests := []struct {
+		name         string
+		load         func(ctx context.Context) ([]hivemind.Tournament, error)
+		wantFetched  int
+		wantNextPage int
+	}{
+		{
+			name:         "a scene with plenty stops after the first page",
+			load:         func(ctx context.Context) ([]hivemind.Tournament, error) { return tournamentList.Scene(ctx, "kqpdx", 5) },
+			wantFetched:  10,
+			wantNextPage: 2,
+		},
+		{
+			name:         "a scene needing more reads on",
+			load:         func(ctx context.Context) ([]hivemind.Tournament, error) { return tournamentList.Scene(ctx, "KQSEA", 3) },
+			wantFetched:  20,
+			wantNextPage: 3,
+		},
+		{
+			name:        "a scene without enough reads everything",
+			load:        func(ctx context.Context) ([]hivemind.Tournament, error) { return tournamentList.Scene(ctx, "kqchi", 1) },
+			wantFetched: 25,
+		},
+		{
+			name:        "all",
+			load:        tournamentList.All,
+			wantFetched: 25,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveFakeHiveMind(t, fx)
+
+			fetched, err := tt.load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fetched) != tt.wantFetched {
+				t.Errorf("got %v tournaments, want %v", len(fetched), tt.wantFetched)
+			}
+			tournamentList.mu.Lock()
+			nextPage := tournamentList.nextPage
+			tournamentList.mu.Unlock()
+			if nextPage != tt.wantNextPage {
+				t.Errorf("the next page to fetch is %v, want %v", nextPage, tt.wantNextPage)
+			}
+		})
+	}
+}
+
+func TestSearchTournaments(t *testing.T) {
+	fx := &hivemindtest.Fixtures{}
+	for i := 1; i <= 25; i++ {
+		fx.Tournaments = append(fx.Tournaments, map[string]interface{}{
+			"id": i, "name": fmt.Sprintf("Mixer %d", i), "date": day(-i), "scene_name": "kqpdx",
+		})
+	}
+	serveFakeHiveMind(t, fx)
+	ctx := context.Background()
+
+	// The lobby's lookup only reads the first page
+	lobby, err := GetSceneTournies(ctx, "kqpdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tournamentIDs(lobby); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}) {
+		t.Errorf("the lobby offers %v, want the newest %v", got, tournamentLimit)
+	}
+
+	// Searching reads the rest, past what the lobby already fetched
+	results, err := SearchTournaments(ctx, TournamentFilter{Scene: "kqpdx", Page: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tournamentIDs(results.Tournaments); results.Total != 25 || !reflect.DeepEqual(got, []int{21, 22, 23, 24, 25}) {
+		t.Errorf("got %v of %v on page 2, want 21 to 25 of 25", got, results.Total)
+	}
+
+	// HiveMind gets a new tournament, which only shows up once the cache goes stale
+	fx.Tournaments = append([]map[string]interface{}{{"id": 26, "name": "Mixer 26", "date": day(0), "scene_name": "kqpdx"}}, fx.Tournaments...)
+	server := hivemindtest.Start(fx)
+	defer server.Close()
+	hm = hivemind.NewClient("", hivemind.WithBaseURL(server.URL+"/api"))
+	if results, err = SearchTournaments(ctx, TournamentFilter{}); err != nil || results.Total != 25 {
+		t.Errorf("got %v tournaments and error %v from a fresh cache, want the 25 cached", results.Total, err)
+	}
+	tournamentList.mu.Lock()
+	tournamentList.started = time.Now().Add(-2 * tournamentCacheTTL)
+	tournamentList.mu.Unlock()
+	if results, err = SearchTournaments(ctx, TournamentFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if results.Total != 26 || results.Tournaments[0].ID != 26 {
+		t.Errorf("got %v tournaments starting with %v after the cache went stale, want 26 starting with 26", results.Total, results.Tournaments[0].ID)
+	}
+}
@@ -0,0 +1,206 @@
+// Package hivemind is a small client for the kqhivemind.com tournament API.
+//
+// Every method takes a context and returns an error instead of exiting, so a
+// bad response in the middle of a draft can be shown to the organizer rather
+// than taking the whole server down.
+package hivemind
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the root of the public HiveMind API.
+const DefaultBaseURL = "https://kqhivemind.com/api"
+
+// Client talks to the HiveMind API. The zero value is not usable, use NewClient.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL points the client at a different API root, e.g. a local fake.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client (10s timeout).
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a transient failure is retried and the
+// initial backoff, which doubles after every attempt.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// NewClient returns a client that authenticates with the given API key.
+func NewClient(apiKey string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    DefaultBaseURL,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 3,
+		backoff:    250 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the API root the client is using.
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// APIError is returned when HiveMind answers with a non-success status code.
+type APIError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("hivemind: %s %s returned %d: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether err is an APIError with a 404 status.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Page is one page of a paginated list endpoint.
+type Page[T any] struct {
+	Count   int    `json:"count"`
+	Next    string `json:"next"`
+	Results []T    `json:"results"`
+}
+
+// endpoint builds the URL for a path under the API root with the given query.
+func (c *Client) endpoint(path string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("format", "json")
+	return fmt.Sprintf("%s/%s/?%s", c.baseURL, strings.Trim(path, "/"), query.Encode())
+}
+
+// do sends a request, retrying transient failures, and decodes a JSON response
+// into out when out is non-nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("hivemind: encoding %s body: %w", path, err)
+		}
+	}
+
+	target := c.endpoint(path, query)
+	wait := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, target, payload, out)
+		if err == nil || attempt >= c.maxRetries || !retryable(method, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, method, target string, payload []byte, out interface{}) error {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("hivemind: building request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Token "+c.apiKey)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("hivemind: %s %s: %w", method, target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{Method: method, URL: target, StatusCode: resp.StatusCode, Body: string(data)}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("hivemind: decoding %s response: %w", target, err)
+	}
+	return nil
+}
+
+// retryable decides whether a failed attempt is worth repeating. A POST is only
+// repeated when HiveMind explicitly rejected it before doing any work, so a
+// flaky connection can't create the same team twice.
+func retryable(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return true
+		case http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
+			return method != http.MethodPost
+		}
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return method != http.MethodPost
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package hivemind
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedServer answers each request with the next status in statuses, repeating the last one, and
+// records when each request arrived. A status of 0 drops the connection without answering.
+type scriptedServer struct {
+	mu       sync.Mutex
+	statuses []int
+	arrivals []time.Time
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status := s.statuses[min(len(s.arrivals), len(s.statuses)-1)]
+	s.arrivals = append(s.arrivals, time.Now())
+	s.mu.Unlock()
+
+	if status == 0 {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+	w.WriteHeader(status)
+	if status == http.StatusOK {
+		w.Write([]byte(`{"id": 7, "name": "Red", "tournament": 1}`))
+	} else {
+		w.Write([]byte("try again"))
+	}
+}
+
+func (s *scriptedServer) calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.arrivals)
+}
+
+// startScripted serves statuses and returns a client for it that retries twice
+func startScripted(t *testing.T, backoff time.Duration, statuses ...int) (*Client, *scriptedServer) {
+	t.Helper()
+	script := &scriptedServer{statuses: statuses}
+	server := httptest.NewServer(script)
+	t.Cleanup(server.Close)
+	return NewClient("", WithBaseURL(server.URL), WithRetries(2, backoff)), script
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		statuses   []int
+		wantCalls  int
+		wantErr    bool
+		wantStatus int // The APIError's status, if the call ends with one
+	}{
+		{name: "GET succeeds first time", method: "GET", statuses: []int{200}, wantCalls: 1},
+		{name: "GET retried after 503", method: "GET", statuses: []int{503, 200}, wantCalls: 2},
+		{name: "GET retried after 500 and 502", method: "GET", statuses: []int{500, 502, 200}, wantCalls: 3},
+		{name: "GET gives up after max retries", method: "GET", statuses: []int{504}, wantCalls: 3, wantErr: true, wantStatus: 504},
+		{name: "GET retried after a dropped connection", method: "GET", statuses: []int{0, 200}, wantCalls: 2},
+		{name: "GET not retried after 404", method: "GET", statuses: []int{404, 200}, wantCalls: 1, wantErr: true, wantStatus: 404},
+		{name: "POST retried after 429", method: "POST", statuses: []int{429, 200}, wantCalls: 2},
+		{name: "POST retried after 503", method: "POST", statuses: []int{503, 503, 200}, wantCalls: 3},
+		{name: "POST not retried after 500", method: "POST", statuses: []int{500, 200}, wantCalls: 1, wantErr: true, wantStatus: 500},
+		{name: "POST not retried after 502", method: "POST", statuses: []int{502, 200}, wantCalls: 1, wantErr: true, wantStatus: 502},
+		{name: "POST not retried after a dropped connection", method: "POST", statuses: []int{0, 200}, wantCalls: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, script := startScripted(t, time.Millisecond, tt.statuses...)
+
+			var err error
+			if tt.method == "POST" {
+				_, err = client.CreateTeam(context.Background(), 1, "Red")
+			} else {
+				_, err = client.Team(context.Background(), 7)
+			}
+
+			if got := script.calls(); got != tt.wantCalls {
+				t.Errorf("made %d calls, want %d", got, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantStatus == 0 {
+				return
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %T %v, want an *APIError", err, err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Method != tt.method || apiErr.Body != "try again" {
+				t.Errorf("got %+v, want a %s returning %d with the response body", apiErr, tt.method, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	backoff := 20 * time.Millisecond
+	client, script := startScripted(t, backoff, 503, 503, 200)
+
+	if _, err := client.Team(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	if len(script.arrivals) != 3 {
+		t.Fatalf("made %d calls, want 3", len(script.arrivals))
+	}
+
+	// The wait doubles after every attempt
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := script.arrivals[i+1].Sub(script.arrivals[i]); gap < want {
+			t.Errorf("retry %d came %v after the last attempt, want at least %v", i+1, gap, want)
+		}
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	client, script := startScripted(t, time.Hour, 503)
+
+	// Cancelling while waiting to retry returns straight away
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Team(ctx, 7); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the context's", err)
+	}
+	if got := script.calls(); got != 1 {
+		t.Errorf("made %d calls, want 1", got)
+	}
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{err: &APIError{StatusCode: http.StatusNotFound}, want: true},
+		{err: &APIError{StatusCode: http.StatusInternalServerError}, want: false},
+		{err: errors.New("not found"), want: false},
+		{err: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := IsNotFound(tt.err); got != tt.want {
+			t.Errorf("IsNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,474 @@
+// Package hivemindtest provides a fake HiveMind API for local development and
+// tests. It serves the scene, tournament, player, team, match and
+// player-info-field endpoints from in-memory data seeded from JSON fixtures, so
+// a whole draft can be run without an API key or touching real tournaments.
+package hivemindtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultPageSize is how many results a list endpoint returns per page.
+const DefaultPageSize = 10
+
+// FormField is a registration field tied to a tournament.
+type FormField struct {
+	ID               int      `json:"id"`
+	Tournament       int      `json:"tournament"`
+	FieldName        string   `json:"field_name"`
+	FieldSlug        string   `json:"field_slug"`
+	FieldDescription string   `json:"field_description"`
+	FieldType        string   `json:"field_type,omitempty"`
+	Choices          []string `json:"choices,omitempty"`
+	IsRequired       bool     `json:"is_required,omitempty"`
+}
+
+// Team is a team row as stored by the fake.
+type Team struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Tournament int    `json:"tournament"`
+}
+
+// Match is a match row as stored by the fake.
+type Match struct {
+	ID         int  `json:"id"`
+	Tournament int  `json:"tournament"`
+	BlueTeam   int  `json:"blue_team"`
+	GoldTeam   int  `json:"gold_team"`
+	BlueScore  int  `json:"blue_score"`
+	GoldScore  int  `json:"gold_score"`
+	IsComplete bool `json:"is_complete"`
+}
+
+// Fixtures is the seed data for a Server. Players are kept as raw objects so
+// fixtures can carry the same per-tournament form field keys HiveMind returns.
+type Fixtures struct {
+	Scenes      []map[string]interface{} `json:"scenes"`
+	Tournaments []map[string]interface{} `json:"tournaments"`
+	FormFields  []FormField              `json:"form_fields"`
+	Players     []map[string]interface{} `json:"players"`
+	Teams       []Team                   `json:"teams"`
+	Matches     []Match                  `json:"matches"`
+}
+
+// LoadFixtures reads fixtures from a JSON file.
+func LoadFixtures(path string) (*Fixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fx Fixtures
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("parsing fixtures %s: %w", path, err)
+	}
+	return &fx, nil
+}
+
+// Server is an in-memory HiveMind. It implements http.Handler and expects to
+// be mounted at the API root (the equivalent of https://kqhivemind.com/api).
+type Server struct {
+	// PageSize is how many results a list endpoint returns per page.
+	PageSize int
+
+	mu          sync.Mutex
+	scenes      []map[string]interface{}
+	tournaments []map[string]interface{}
+	formFields  []FormField
+	players     []map[string]interface{}
+	teams       []Team
+	matches     []Match
+	nextID      int
+}
+
+// NewServer returns a fake seeded with a copy of the given fixtures.
+func NewServer(fx *Fixtures) *Server {
+	s := &Server{PageSize: DefaultPageSize, nextID: 1}
+	if fx == nil {
+		return s
+	}
+
+	// Round-trip through JSON so the fake never shares maps with the caller
+	var seed Fixtures
+	data, _ := json.Marshal(fx)
+	_ = json.Unmarshal(data, &seed)
+
+	s.scenes = seed.Scenes
+	s.tournaments = seed.Tournaments
+	s.formFields = seed.FormFields
+	s.players = seed.Players
+	s.teams = seed.Teams
+	s.matches = seed.Matches
+
+	for _, t := range s.tournaments {
+		s.bumpID(intField(t, "id"))
+	}
+	for _, p := range s.players {
+		s.bumpID(intField(p, "id"))
+	}
+	for _, t := range s.teams {
+		s.bumpID(t.ID)
+	}
+	for _, m := range s.matches {
+		s.bumpID(m.ID)
+	}
+	return s
+}
+
+// Start runs the fake on a random local port. The returned server's URL plus
+// "/api" can be handed to hivemind.WithBaseURL.
+func Start(fx *Fixtures) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", NewServer(fx)))
+	return httptest.NewServer(mux)
+}
+
+// Teams returns a snapshot of the teams currently stored by the fake.
+func (s *Server) Teams() []Team {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Team(nil), s.teams...)
+}
+
+// PlayerTeam returns the team ID a player is on, or 0 if they have none.
+func (s *Server) PlayerTeam(playerID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.findPlayer(playerID); p != nil {
+		return intField(p, "team")
+	}
+	return 0
+}
+
+func (s *Server) bumpID(id int) {
+	if id >= s.nextID {
+		s.nextID = id + 1
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Paths look like /tournament/<resource>/ or /tournament/<resource>/<id>/, plus /game/scene/ for the scene list
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || (parts[0] != "tournament" && parts[0] != "game") {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	resource := parts[1]
+	id := 0
+	if len(parts) == 3 {
+		var err error
+		id, err = strconv.Atoi(parts[2])
+		if err != nil {
+			writeError(w, http.StatusNotFound, "Not found.")
+			return
+		}
+	} else if len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case parts[0] == "game" && resource == "scene" && id == 0 && r.Method == http.MethodGet:
+		s.list(w, r, filter(s.scenes, func(scene map[string]interface{}) bool { return true }))
+	case parts[0] == "game":
+		writeError(w, http.StatusNotFound, "Not found.")
+	case resource == "tournament" && id == 0 && r.Method == http.MethodGet:
+		s.list(w, r, filter(s.tournaments, func(t map[string]interface{}) bool { return true }))
+	case resource == "tournament" && r.Method == http.MethodGet:
+		s.getTournament(w, id)
+	case resource == "player-info-field" && id == 0 && r.Method == http.MethodGet:
+		tournamentID := queryInt(r, "tournament_id")
+		s.list(w, r, filter(s.formFields, func(f FormField) bool {
+			return tournamentID == 0 || f.Tournament == tournamentID
+		}))
+	case resource == "player" && id == 0 && r.Method == http.MethodGet:
+		tournamentID := queryInt(r, "tournament_id")
+		s.list(w, r, filter(s.players, func(p map[string]interface{}) bool {
+			return tournamentID == 0 || intField(p, "tournament") == tournamentID
+		}))
+	case resource == "player" && id == 0 && r.Method == http.MethodPost:
+		s.createPlayer(w, r)
+	case resource == "player" && id != 0 && r.Method == http.MethodPatch:
+		s.patchPlayer(w, r, id)
+	case resource == "team" && id == 0 && r.Method == http.MethodGet:
+		tournamentID := queryInt(r, "tournament_id")
+		s.list(w, r, filter(s.teams, func(t Team) bool {
+			return tournamentID == 0 || t.Tournament == tournamentID
+		}))
+	case resource == "team" && id == 0 && r.Method == http.MethodPost:
+		s.createTeam(w, r)
+	case resource == "team" && id != 0 && r.Method == http.MethodGet:
+		s.getTeam(w, id)
+	case resource == "team" && id != 0 && r.Method == http.MethodDelete:
+		s.deleteTeam(w, id)
+	case resource == "match" && id == 0 && r.Method == http.MethodGet:
+		tournamentID := queryInt(r, "tournament_id")
+		s.list(w, r, filter(s.matches, func(m Match) bool {
+			return tournamentID == 0 || m.Tournament == tournamentID
+		}))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("Method \"%s\" not allowed.", r.Method))
+	}
+}
+
+// list writes one page of results in the same shape as HiveMind's paginated endpoints.
+func (s *Server) list(w http.ResponseWriter, r *http.Request, results []interface{}) {
+	pageSize := s.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	page := queryInt(r, "page")
+	if page == 0 {
+		page = 1
+	}
+
+	start := (page - 1) * pageSize
+	if page < 1 || (start >= len(results) && page != 1) {
+		writeError(w, http.StatusNotFound, "Invalid page.")
+		return
+	}
+	end := start + pageSize
+	if end > len(results) {
+		end = len(results)
+	}
+
+	var next interface{}
+	if end < len(results) {
+		q := r.URL.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next = "http://" + r.Host + "/api" + r.URL.Path + "?" + q.Encode()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"count":    len(results),
+		"next":     next,
+		"previous": nil,
+		"results":  results[start:end],
+	})
+}
+
+func (s *Server) getTournament(w http.ResponseWriter, id int) {
+	for _, t := range s.tournaments {
+		if intField(t, "id") == id {
+			writeJSON(w, http.StatusOK, t)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not found.")
+}
+
+func (s *Server) patchPlayer(w http.ResponseWriter, r *http.Request, id int) {
+	player := s.findPlayer(id)
+	if player == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	var update map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON parse error - "+err.Error())
+		return
+	}
+
+	if team, ok := update["team"]; ok {
+		if team != nil {
+			teamID := intValue(team)
+			if s.findTeam(teamID) == nil {
+				writeJSON(w, http.StatusBadRequest, map[string][]string{
+					"team": {fmt.Sprintf("Invalid pk \"%v\" - object does not exist.", team)},
+				})
+				return
+			}
+			team = float64(teamID)
+		}
+		player["team"] = team
+	}
+
+	writeJSON(w, http.StatusOK, player)
+}
+
+func (s *Server) createPlayer(w http.ResponseWriter, r *http.Request) {
+	var player map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&player); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON parse error - "+err.Error())
+		return
+	}
+	if name, _ := player["name"].(string); strings.TrimSpace(name) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string][]string{"name": {"This field may not be blank."}})
+		return
+	}
+
+	tournamentID := intField(player, "tournament")
+	found := false
+	for _, t := range s.tournaments {
+		found = found || intField(t, "id") == tournamentID
+	}
+	if !found {
+		writeJSON(w, http.StatusBadRequest, map[string][]string{
+			"tournament": {fmt.Sprintf("Invalid pk \"%v\" - object does not exist.", player["tournament"])},
+		})
+		return
+	}
+
+	player["id"] = float64(s.nextID)
+	s.nextID++
+	player["tournament"] = float64(tournamentID)
+	player["team"] = nil
+	s.players = append(s.players, player)
+
+	writeJSON(w, http.StatusCreated, player)
+}
+
+func (s *Server) createTeam(w http.ResponseWriter, r *http.Request) {
+	var team Team
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		writeError(w, http.StatusBadRequest, "JSON parse error - "+err.Error())
+		return
+	}
+	if strings.TrimSpace(team.Name) == "" {
+		writeJSON(w, http.StatusBadRequest, map[string][]string{"name": {"This field may not be blank."}})
+		return
+	}
+
+	team.ID = s.nextID
+	s.nextID++
+	s.teams = append(s.teams, team)
+
+	writeJSON(w, http.StatusCreated, team)
+}
+
+func (s *Server) getTeam(w http.ResponseWriter, id int) {
+	team := s.findTeam(id)
+	if team == nil {
+		writeError(w, http.StatusNotFound, "Not found.")
+		return
+	}
+
+	type playerRef struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	members := []playerRef{}
+	for _, p := range s.players {
+		if intField(p, "team") == id {
+			members = append(members, playerRef{ID: intField(p, "id"), Name: fmt.Sprint(p["name"])})
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":         team.ID,
+		"name":       team.Name,
+		"tournament": team.Tournament,
+		"players":    members,
+	})
+}
+
+func (s *Server) deleteTeam(w http.ResponseWriter, id int) {
+	for i, t := range s.teams {
+		if t.ID != id {
+			continue
+		}
+		s.teams = append(s.teams[:i], s.teams[i+1:]...)
+
+		// HiveMind nulls out the team on any players that were on it
+		for _, p := range s.players {
+			if intField(p, "team") == id {
+				p["team"] = nil
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeError(w, http.StatusNotFound, "Not found.")
+}
+
+func (s *Server) findPlayer(id int) map[string]interface{} {
+	for _, p := range s.players {
+		if intField(p, "id") == id {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) findTeam(id int) *Team {
+	for i := range s.teams {
+		if s.teams[i].ID == id {
+			return &s.teams[i]
+		}
+	}
+	return nil
+}
+
+// filter keeps the items matching keep, sorted by ID so paging is stable.
+func filter[T any](items []T, keep func(T) bool) []interface{} {
+	results := []interface{}{}
+	for _, item := range items {
+		if keep(item) {
+			results = append(results, item)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return idOf(results[i]) < idOf(results[j]) })
+	return results
+}
+
+func idOf(item interface{}) int {
+	switch v := item.(type) {
+	case map[string]interface{}:
+		return intField(v, "id")
+	case FormField:
+		return v.ID
+	case Team:
+		return v.ID
+	case Match:
+		return v.ID
+	}
+	return 0
+}
+
+func intField(obj map[string]interface{}, key string) int {
+	return intValue(obj[key])
+}
+
+func intValue(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
+
+func queryInt(r *http.Request, key string) int {
+	i, _ := strconv.Atoi(r.URL.Query().Get(key))
+	return i
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
@@ -0,0 +1,47 @@
+package hivemind
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Match is one match played between two teams at a tournament. The scores
+// count games won, and the teams are empty until the bracket fills them in.
+type Match struct {
+	ID         int  `json:"id"`
+	Tournament int  `json:"tournament"`
+	BlueTeam   int  `json:"blue_team"`
+	GoldTeam   int  `json:"gold_team"`
+	BlueScore  int  `json:"blue_score"`
+	GoldScore  int  `json:"gold_score"`
+	IsComplete bool `json:"is_complete"`
+}
+
+// Matches returns every match for a tournament, following pages until the
+// list is exhausted.
+func (c *Client) Matches(ctx context.Context, tournamentID int) ([]Match, error) {
+	var matches []Match
+
+	for page := 1; ; page++ {
+		var resp Page[Match]
+		query := url.Values{
+			"tournament_id": {strconv.Itoa(tournamentID)},
+			"page":          {strconv.Itoa(page)},
+		}
+		err := c.do(ctx, "GET", "tournament/match", query, nil, &resp)
+		if page > 1 && IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, resp.Results...)
+		if resp.Next == "" || len(resp.Results) == 0 {
+			break
+		}
+	}
+
+	return matches, nil
+}
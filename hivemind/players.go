@@ -0,0 +1,81 @@
+package hivemind
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// FormField describes one of a tournament's custom registration questions.
+// Player answers are returned under FieldName on the player object.
+type FormField struct {
+	ID               int    `json:"id"`
+	FieldName        string `json:"field_name"`
+	FieldSlug        string `json:"field_slug"`
+	FieldDescription string `json:"field_description"`
+	// FieldType, Choices and IsRequired are empty when the tournament
+	// doesn't set them.
+	FieldType  string   `json:"field_type,omitempty"`
+	Choices    []string `json:"choices,omitempty"`
+	IsRequired bool     `json:"is_required,omitempty"`
+}
+
+// FormFields returns the registration form fields for a tournament.
+func (c *Client) FormFields(ctx context.Context, tournamentID int) ([]FormField, error) {
+	var resp Page[FormField]
+	query := url.Values{"tournament_id": {strconv.Itoa(tournamentID)}}
+	if err := c.do(ctx, "GET", "tournament/player-info-field", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Players returns every registered player for a tournament, following pages
+// until the list is exhausted. Players are returned as raw JSON objects since
+// the form field answers are keyed by per-tournament field names.
+func (c *Client) Players(ctx context.Context, tournamentID int) ([]map[string]interface{}, error) {
+	var players []map[string]interface{}
+
+	for page := 1; ; page++ {
+		var resp Page[map[string]interface{}]
+		query := url.Values{
+			"tournament_id": {strconv.Itoa(tournamentID)},
+			"page":          {strconv.Itoa(page)},
+		}
+		err := c.do(ctx, "GET", "tournament/player", query, nil, &resp)
+		if page > 1 && IsNotFound(err) {
+			// Past the last page
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		players = append(players, resp.Results...)
+		if resp.Next == "" || len(resp.Results) == 0 {
+			break
+		}
+	}
+
+	return players, nil
+}
+
+// CreatePlayer registers a player for a tournament. The player object carries
+// "tournament", "name" and any other player attributes, plus form field answers
+// keyed by field name. It returns the player as created by HiveMind.
+func (c *Client) CreatePlayer(ctx context.Context, player map[string]interface{}) (map[string]interface{}, error) {
+	var created map[string]interface{}
+	if err := c.do(ctx, "POST", "tournament/player", nil, player, &created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// SetPlayerTeam moves a player onto a team. A teamID of 0 clears the player's team.
+func (c *Client) SetPlayerTeam(ctx context.Context, playerID, teamID int) error {
+	update := map[string]interface{}{"team": nil}
+	if teamID != 0 {
+		update["team"] = teamID
+	}
+	return c.do(ctx, "PATCH", "tournament/player/"+strconv.Itoa(playerID), nil, update, nil)
+}
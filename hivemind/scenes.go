@@ -0,0 +1,41 @@
+package hivemind
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Scene is a local Killer Queen scene, e.g. kqpdx.
+type Scene struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	DisplayName     string `json:"display_name"`
+	BackgroundColor string `json:"background_color"`
+	ForegroundColor string `json:"foreground_color"`
+}
+
+// Scenes returns every scene registered with HiveMind.
+func (c *Client) Scenes(ctx context.Context) ([]Scene, error) {
+	var scenes []Scene
+
+	for page := 1; ; page++ {
+		var resp Page[Scene]
+		query := url.Values{"page": {strconv.Itoa(page)}}
+		err := c.do(ctx, "GET", "game/scene", query, nil, &resp)
+		if page > 1 && IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scenes = append(scenes, resp.Results...)
+
+		if resp.Next == "" || len(resp.Results) == 0 {
+			break
+		}
+	}
+
+	return scenes, nil
+}
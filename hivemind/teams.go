@@ -0,0 +1,73 @@
+package hivemind
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Team is a team registered for a tournament.
+type Team struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Tournament int    `json:"tournament"`
+}
+
+// TeamDetail is a single team along with the IDs of its players.
+type TeamDetail struct {
+	Team
+	Players []struct {
+		ID int `json:"id"`
+	} `json:"players"`
+}
+
+// Teams returns all teams for a tournament.
+func (c *Client) Teams(ctx context.Context, tournamentID int) ([]Team, error) {
+	var teams []Team
+
+	for page := 1; ; page++ {
+		var resp Page[Team]
+		query := url.Values{
+			"tournament_id": {strconv.Itoa(tournamentID)},
+			"page":          {strconv.Itoa(page)},
+		}
+		err := c.do(ctx, "GET", "tournament/team", query, nil, &resp)
+		if page > 1 && IsNotFound(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		teams = append(teams, resp.Results...)
+		if resp.Next == "" || len(resp.Results) == 0 {
+			break
+		}
+	}
+
+	return teams, nil
+}
+
+// Team fetches a single team and its player IDs.
+func (c *Client) Team(ctx context.Context, teamID int) (*TeamDetail, error) {
+	var team TeamDetail
+	if err := c.do(ctx, "GET", "tournament/team/"+strconv.Itoa(teamID), nil, nil, &team); err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// CreateTeam adds a team to a tournament and returns it as created by HiveMind.
+func (c *Client) CreateTeam(ctx context.Context, tournamentID int, name string) (*Team, error) {
+	team := Team{Name: name, Tournament: tournamentID}
+	var created Team
+	if err := c.do(ctx, "POST", "tournament/team", nil, team, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// DeleteTeam removes a team from its tournament.
+func (c *Client) DeleteTeam(ctx context.Context, teamID int) error {
+	return c.do(ctx, "DELETE", "tournament/team/"+strconv.Itoa(teamID), nil, nil, nil)
+}
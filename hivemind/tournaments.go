@@ -0,0 +1,34 @@
+package hivemind
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+// Tournament is a HiveMind tournament.
+type Tournament struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Date      string `json:"date"`
+	SceneName string `json:"scene_name"`
+}
+
+// Tournaments returns one page (1-based) of the tournament list.
+func (c *Client) Tournaments(ctx context.Context, page int) (*Page[Tournament], error) {
+	var resp Page[Tournament]
+	query := url.Values{"page": {strconv.Itoa(page)}}
+	if err := c.do(ctx, "GET", "tournament/tournament", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Tournament fetches a single tournament by ID.
+func (c *Client) Tournament(ctx context.Context, id int) (*Tournament, error) {
+	var tournament Tournament
+	if err := c.do(ctx, "GET", "tournament/tournament/"+strconv.Itoa(id), nil, nil, &tournament); err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
@@ -0,0 +1,248 @@
+// Package kvstore is a tiny embedded key-value store backed by a single
+// append-only log file. Every write is appended and synced before it returns,
+// so the last successful write always survives a crash or restart. The log is
+// compacted when it is reopened and whenever it grows to more than twice the
+// live keys, so a long-running process doesn't fill the disk with old values.
+package kvstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// compactMinRecords is how long the log must be before writes trigger a
+// compaction, so small stores aren't rewritten on every other write.
+var compactMinRecords = 1000
+
+// ErrClosed is returned when using a DB after Close.
+var ErrClosed = errors.New("kvstore: database is closed")
+
+// record is one line of the log.
+type record struct {
+	Key     string `json:"k"`
+	Value   []byte `json:"v,omitempty"`
+	Deleted bool   `json:"d,omitempty"`
+}
+
+// DB is an open key-value store. It is safe for concurrent use.
+type DB struct {
+	mu      sync.RWMutex
+	path    string
+	file    *os.File
+	data    map[string][]byte
+	records int // Lines in the log, live or not
+}
+
+// Open opens the store at path, creating it if needed.
+func Open(path string) (*DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	db := &DB{path: path, data: make(map[string][]byte)}
+	if err := db.replay(); err != nil {
+		return nil, err
+	}
+	if err := db.compact(); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	db.file = file
+	return db, nil
+}
+
+// replay rebuilds the in-memory map from the log. A torn final line from a
+// crash mid-write is ignored.
+func (db *DB) replay() error {
+	file, err := os.Open(db.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		db.records++
+		if rec.Deleted {
+			delete(db.data, rec.Key)
+		} else {
+			db.data[rec.Key] = rec.Value
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the log with only the live keys.
+func (db *DB) compact() error {
+	tmp := db.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, key := range db.sortedKeys("") {
+		if err := enc.Encode(record{Key: key, Value: db.data[key]}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, db.path); err != nil {
+		return err
+	}
+	db.records = len(db.data)
+	return nil
+}
+
+// Compact rewrites the log with only the live keys while the store stays open.
+func (db *DB) Compact() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.rotate()
+}
+
+// rotate compacts the log and reopens it for appending. If compacting fails
+// the old log is still complete and stays open.
+func (db *DB) rotate() error {
+	if db.file == nil {
+		return ErrClosed
+	}
+	if err := db.compact(); err != nil {
+		return fmt.Errorf("kvstore: compacting %s: %w", db.path, err)
+	}
+
+	db.file.Close()
+	file, err := os.OpenFile(db.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		db.file = nil
+		return err
+	}
+	db.file = file
+	return nil
+}
+
+// maybeCompact compacts the log once most of it is overwritten or deleted
+// values. The write that triggered it is already safe in the old log, so a
+// failure here is left for the next write to retry.
+func (db *DB) maybeCompact() {
+	if db.records >= compactMinRecords && db.records > 2*len(db.data) {
+		db.rotate()
+	}
+}
+
+func (db *DB) append(rec record) error {
+	if db.file == nil {
+		return ErrClosed
+	}
+
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := db.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("kvstore: writing %s: %w", db.path, err)
+	}
+	db.records++
+	return db.file.Sync()
+}
+
+// Get returns the value stored under key.
+func (db *DB) Get(key string) ([]byte, bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	value, ok := db.data[key]
+	return value, ok
+}
+
+// Put stores value under key, replacing any previous value.
+func (db *DB) Put(key string, value []byte) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.append(record{Key: key, Value: value}); err != nil {
+		return err
+	}
+	db.data[key] = append([]byte(nil), value...)
+	db.maybeCompact()
+	return nil
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (db *DB) Delete(key string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.data[key]; !ok {
+		return nil
+	}
+	if err := db.append(record{Key: key, Deleted: true}); err != nil {
+		return err
+	}
+	delete(db.data, key)
+	db.maybeCompact()
+	return nil
+}
+
+// Keys returns every key with the given prefix in sorted order.
+func (db *DB) Keys(prefix string) []string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.sortedKeys(prefix)
+}
+
+func (db *DB) sortedKeys(prefix string) []string {
+	var keys []string
+	for key := range db.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Close closes the underlying log file.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.file == nil {
+		return nil
+	}
+	err := db.file.Close()
+	db.file = nil
+	return err
+}
@@ -0,0 +1,260 @@
+package kvstore
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// op is one write made to the store in a test
+type op struct {
+	key    string
+	value  string
+	delete bool
+}
+
+func apply(t *testing.T, db *DB, ops []op) {
+	t.Helper()
+	for _, o := range ops {
+		var err error
+		if o.delete {
+			err = db.Delete(o.key)
+		} else {
+			err = db.Put(o.key, []byte(o.value))
+		}
+		if err != nil {
+			t.Fatalf("writing %q: %v", o.key, err)
+		}
+	}
+}
+
+// contents reads every key and value in the store
+func contents(db *DB) map[string]string {
+	got := map[string]string{}
+	for _, key := range db.Keys("") {
+		value, _ := db.Get(key)
+		got[key] = string(value)
+	}
+	return got
+}
+
+// logLines counts the lines in the store's log file
+func logLines(t *testing.T, path string) int {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		lines++
+	}
+	return lines
+}
+
+func TestReopen(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []op
+		want map[string]string
+	}{
+		{
+			name: "puts",
+			ops:  []op{{key: "a", value: "1"}, {key: "b", value: "2"}},
+			want: map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name: "last put wins",
+			ops:  []op{{key: "a", value: "1"}, {key: "a", value: "2"}, {key: "a", value: "3"}},
+			want: map[string]string{"a": "3"},
+		},
+		{
+			name: "deletes",
+			ops:  []op{{key: "a", value: "1"}, {key: "b", value: "2"}, {key: "a", delete: true}},
+			want: map[string]string{"b": "2"},
+		},
+		{
+			name: "put after delete",
+			ops:  []op{{key: "a", value: "1"}, {key: "a", delete: true}, {key: "a", value: "2"}},
+			want: map[string]string{"a": "2"},
+		},
+		{
+			name: "deleting a missing key",
+			ops:  []op{{key: "missing", delete: true}},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "store.db")
+			db, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			apply(t, db, tt.ops)
+			if got := contents(db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("before reopening got %v, want %v", got, tt.want)
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// Reopening replays the log and compacts it down to one line per live key
+			db, err = Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if got := contents(db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("after reopening got %v, want %v", got, tt.want)
+			}
+			if lines := logLines(t, path); lines != len(tt.want) {
+				t.Errorf("compacted log has %v lines, want %v", lines, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestReplaySkipsTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.db")
+	log := `{"k":"a","v":"MQ=="}` + "\n" + `{"k":"b","v":"Mg=="}` + "\n" + `{"k":"c","v":"M`
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	want := map[string]string{"a": "1", "b": "2"}
+	if got := contents(db); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	apply(t, db, []op{{key: "draft/b", value: "x"}, {key: "pairs/1", value: "x"}, {key: "draft/a", value: "x"}})
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"draft/a", "draft/b", "pairs/1"}},
+		{prefix: "draft/", want: []string{"draft/a", "draft/b"}},
+		{prefix: "ratings/", want: nil},
+	}
+	for _, tt := range tests {
+		if got := db.Keys(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Keys(%q) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestClosed(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Put("a", []byte("1")); !errors.Is(err, ErrClosed) {
+		t.Errorf("Put after Close returned %v, want ErrClosed", err)
+	}
+}
+
+func TestCompactWhileOpen(t *testing.T) {
+	old := compactMinRecords
+	compactMinRecords = 10
+	t.Cleanup(func() { compactMinRecords = old })
+
+	overwrites := make([]op, 50)
+	for i := range overwrites {
+		overwrites[i] = op{key: "draft", value: strconv.Itoa(i)}
+	}
+	churn := make([]op, 0, 50)
+	for i := 0; i < 25; i++ {
+		key := "draft/" + strconv.Itoa(i)
+		churn = append(churn, op{key: key, value: "x"}, op{key: key, delete: true})
+	}
+
+	tests := []struct {
+		name     string
+		ops      []op
+		want     map[string]string
+		maxLines int
+	}{
+		{name: "overwriting one key", ops: overwrites, want: map[string]string{"draft": "49"}, maxLines: 10},
+		{name: "adding and deleting keys", ops: churn, want: map[string]string{}, maxLines: 10},
+		{name: "few writes aren't compacted", ops: overwrites[:5], want: map[string]string{"draft": "4"}, maxLines: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "drafts.db")
+			db, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			apply(t, db, tt.ops)
+			if lines := logLines(t, path); lines > tt.maxLines {
+				t.Errorf("the log has %d lines after %d writes, want at most %d", lines, len(tt.ops), tt.maxLines)
+			}
+
+			// Writes after a compaction land in the new log and survive a reopen
+			apply(t, db, []op{{key: "last", value: "write"}})
+			tt.want["last"] = "write"
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+			reopened, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			if got := contents(reopened); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reopened with %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drafts.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apply(t, db, []op{{key: "a", value: "1"}, {key: "a", value: "2"}, {key: "b", value: "1"}, {key: "b", delete: true}})
+
+	if err := db.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := logLines(t, path); lines != 1 {
+		t.Errorf("the log has %d lines after compacting, want 1", lines)
+	}
+	if got := contents(db); !reflect.DeepEqual(got, map[string]string{"a": "2"}) {
+		t.Errorf("got %v after compacting, want a=2", got)
+	}
+
+	db.Close()
+	if err := db.Compact(); !errors.Is(err, ErrClosed) {
+		t.Errorf("compacting a closed store: got %v, want ErrClosed", err)
+	}
+}
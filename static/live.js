@@ -0,0 +1,29 @@
+// Keeps a draft page up to date from the draft's event stream. handlers maps an
+// event type ("pick", "undo", "turn", "clock", "auction" or "teams") to a function
+// that gets the event's data. The browser reconnects on its own and sends the last
+// event it saw, so anything published while it was disconnected gets replayed.
+function watchDraft(draftID, lastEventID, handlers) {
+    if (!window.EventSource) {
+        return null;
+    }
+
+    var source = new EventSource("/drafts/" + draftID + "/events?lastEventID=" + lastEventID);
+
+    Object.keys(handlers).forEach(function (type) {
+        source.addEventListener(type, function (e) {
+            handlers[type](JSON.parse(e.data));
+        });
+    });
+
+    // The server couldn't replay what we missed, start over from a fresh page
+    source.addEventListener("reload", function () {
+        source.close();
+        location.reload();
+    });
+
+    return source;
+}
+
+function reloadPage() {
+    location.reload();
+}